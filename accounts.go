@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Category and name of transfers without description
+const TRANSFER_CATEGORY = "перевод"
+
+// Label of purchases without an account in the balance report
+const NO_ACCOUNT = "-"
+
+// Cut "[карта]" account marker or "[карта>наличные]" transfer marker off the item,
+// returns item without the marker, the account and the account money is moved to
+func cutAccount(s string) (string, string, string, error) {
+	markers := reAccount.FindAllStringSubmatch(s, -1)
+	if len(markers) == 0 {
+		return s, "", "", nil
+	}
+	if len(markers) > 1 {
+		return "", "", "", fmt.Errorf("more than one account: %s", s)
+	}
+	s = reAccount.ReplaceAllString(s, "")
+	from, to, isTransfer := strings.Cut(markers[0][1], ">")
+	from = strings.ToLower(strings.TrimSpace(from))
+	to = strings.ToLower(strings.TrimSpace(to))
+	if from == "" || isTransfer && to == "" {
+		return "", "", "", fmt.Errorf("invalid account format: %s", markers[0][0])
+	}
+	if isTransfer && from == to {
+		return "", "", "", fmt.Errorf("transfer to the same account: %s", markers[0][0])
+	}
+	return s, from, to, nil
+}
+
+// Transfer moves money between accounts and isn't an expense
+func (c *Commodity) isTransfer() bool {
+	return c.toAccount != ""
+}
+
+// Expenses and refunds, without income and transfers
+func (pp Purchases) expenses() Purchases {
+	var result Purchases
+	for _, p := range pp {
+		if p.commodity.isExpense() {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+type accountBalance struct {
+	period          time.Time
+	account         string
+	change, balance int
+}
+
+// Change and running balance per account for every day or month with
+// activity. Expenses decrease the balance of their account, income increases
+// it, transfers move the price from one account to another. Accounts seen before keep
+// appearing in later periods with their balance carried over.
+func (pp Purchases) accountBalances(period string) ([]accountBalance, error) {
+	changes := map[time.Time]map[string]int{}
+	add := func(start time.Time, account string, amount int) {
+		if account == "" {
+			account = NO_ACCOUNT
+		}
+		if changes[start] == nil {
+			changes[start] = map[string]int{}
+		}
+		changes[start][account] += amount
+	}
+	for _, p := range pp {
+		start, err := periodStart(p.date, period)
+		if err != nil {
+			return nil, err
+		}
+		c := p.commodity
+		add(start, c.account, c.flow())
+		if c.isTransfer() {
+			add(start, c.toAccount, c.price)
+		}
+	}
+
+	var periods []time.Time
+	for start := range changes {
+		periods = append(periods, start)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Before(periods[j])
+	})
+
+	var result []accountBalance
+	balances := map[string]int{}
+	for _, start := range periods {
+		for account, change := range changes[start] {
+			balances[account] += change
+		}
+		for _, account := range sortedKeys(balances) {
+			result = append(result, accountBalance{start, account, changes[start][account], balances[account]})
+		}
+	}
+	return result, nil
+}
+
+// Print change and running balance per account and period
+func balanceCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var period string
+	fs := newFlagSet("balance")
+	filter.register(fs)
+	fs.StringVar(&period, "period", "month", "Balance period: day or month")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+	balances, err := pp.accountBalances(period)
+	if err != nil {
+		return err
+	}
+
+	layout := "2006-01"
+	if period == "day" {
+		layout = df
+	}
+	t := &table{columns: []column{
+		{"Period", kindString},
+		{"Account", kindString},
+		{"Change", kindDecimal},
+		{"Balance", kindDecimal},
+	}}
+	for _, b := range balances {
+		t.rows = append(t.rows, []string{
+			b.period.Format(layout),
+			b.account,
+			fmt.Sprintf("%+d", b.change),
+			strconv.Itoa(b.balance),
+		})
+	}
+	return t.writeText(w)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutAccount(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		account   string
+		toAccount string
+		wantErr   bool
+	}{
+		{input: "хлеб (50)", expected: "хлеб (50)"},
+		{input: "хлеб (50) [Карта]", expected: "хлеб (50) ", account: "карта"},
+		{input: "[cash] хлеб (50)", expected: " хлеб (50)", account: "cash"},
+		{input: "[карта > наличные] (5000)", expected: " (5000)", account: "карта", toAccount: "наличные"},
+		{input: "банкомат [карта>наличные] (5000)", expected: "банкомат  (5000)", account: "карта", toAccount: "наличные"},
+		{input: "хлеб (50) []", wantErr: true},
+		{input: "хлеб (50) [карта>]", wantErr: true},
+		{input: "[карта>карта] (50)", wantErr: true},
+		{input: "хлеб [карта] (50) [наличные]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			s, account, toAccount, err := cutAccount(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+			assert.Equal(t, tt.account, account)
+			assert.Equal(t, tt.toAccount, toAccount)
+		})
+	}
+}
+
+func TestNewCommodityTransfer(t *testing.T) {
+	saveGlobals(t)
+
+	c, err := newCommodity("[карта>наличные] (5000)", time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, c.isTransfer())
+	assert.Equal(t, TRANSFER_CATEGORY, c.category)
+	assert.Equal(t, TRANSFER_CATEGORY, c.name)
+	assert.Equal(t, "карта", c.account)
+	assert.Equal(t, "наличные", c.toAccount)
+	assert.Equal(t, 5000, c.price)
+
+	c, err = newCommodity("Маша/снятие [карта>наличные] (1000)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "маша", c.person)
+	assert.Equal(t, "снятие", c.category)
+
+	c, err = newCommodity("хлеб (50) [карта]", time.Time{})
+	assert.NoError(t, err)
+	assert.False(t, c.isTransfer())
+	assert.Equal(t, "хлеб", c.name)
+	assert.Equal(t, "карта", c.account)
+}
+
+func TestGetPurchasesRowAccount(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items", "Account"},
+		{"01.03.2024", "хлеб (50), молоко (90) [наличные]", "Карта"},
+		{"02.03.2024", "кофе (200)", ""},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	var accounts []string
+	for _, p := range purchases {
+		accounts = append(accounts, p.commodity.account)
+	}
+	assert.Equal(t, []string{"карта", "наличные", ""}, accounts)
+}
+
+func TestGetPurchasesExtraColumn(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items", "Comment", "account"},
+		{"01.03.2024", "хлеб (50)", "к ужину", "Карта"},
+		{"02.03.2024", "кофе (200)", "с коллегами"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	assert.Equal(t, "карта", purchases[0].commodity.account)
+	assert.Equal(t, "", purchases[1].commodity.account, "columns without Account header are ignored")
+
+	purchases, errors = getPurchases([][]string{{"Date", "Items"}, {"01.03.2024", "хлеб (50)", "к ужину"}})
+	assert.Empty(t, errors)
+	assert.Equal(t, "", purchases[0].commodity.account)
+}
+
+func TestExpenses(t *testing.T) {
+	purchases := Purchases{
+		{commodity: &Commodity{name: "хлеб", price: 50, account: "карта"}},
+		{commodity: &Commodity{name: TRANSFER_CATEGORY, price: 5000, account: "карта", toAccount: "наличные"}},
+	}
+	expenses := purchases.expenses()
+	assert.Len(t, expenses, 1)
+	assert.Equal(t, "хлеб", expenses[0].commodity.name)
+}
+
+func TestAccountBalances(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items", "Account"},
+		{"01.03.2024", "[карта>наличные] (5000), хлеб (50) [наличные]", "карта"},
+		{"15.03.2024", "кафе (900)", "карта"},
+		{"02.04.2024", "такси (300) [наличные]", ""},
+		{"03.04.2024", "кофе (200)", ""},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	balances, err := purchases.accountBalances("month")
+	assert.NoError(t, err)
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []accountBalance{
+		{march, "карта", -5900, -5900},
+		{march, "наличные", 4950, 4950},
+		{april, NO_ACCOUNT, -200, -200},
+		{april, "карта", 0, -5900},
+		{april, "наличные", -300, 4650},
+	}, balances)
+
+	_, err = purchases.accountBalances("year")
+	assert.Error(t, err)
+}
+
+func TestBalanceCommand(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.03.2024", "[карта>наличные] (5000), хлеб (50) [наличные]"},
+		{"02.03.2024", "кафе (900) [карта]"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	var buf bytes.Buffer
+	assert.NoError(t, balanceCommand(&buf, purchases, nil))
+	expected := `Period   Account   Change  Balance
+2024-03  карта     -5900   -5900
+2024-03  наличные  +4950   4950
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, balanceCommand(&buf, purchases, []string{"-period", "day", "-to", "01.03.2024"}))
+	expected = `Period      Account   Change  Balance
+01.03.2024  карта     -5000   -5000
+01.03.2024  наличные  +4950   4950
+`
+	assert.Equal(t, expected, buf.String())
+}
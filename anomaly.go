@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	// Modified z-score above which a price is an outlier, as proposed by Iglewicz and Hoaglin
+	ANOMALY_THRESHOLD = 3.5
+	// Minimum number of purchases to learn a typical price from
+	ANOMALY_MIN_SAMPLES = 5
+	// Minimum spread as a share of median, so stable prices which rise
+	// a little aren't outliers
+	ANOMALY_MIN_SPREAD = 0.1
+)
+
+// Expense item as written in a row, shared purchases are joined back
+type expenseItem struct {
+	purchase *Purchase
+	price    int
+	amount   float64 // in original currency
+	quantity float64
+}
+
+// Unit price if quantity is known, price otherwise
+func (i *expenseItem) value() float64 {
+	if i.quantity > 0 {
+		return float64(i.price) / i.quantity
+	}
+	return float64(i.price)
+}
+
+// Expenses with parts of shared purchases joined back into items as written
+func (pp Purchases) expenseItems() []*expenseItem {
+	var items []*expenseItem
+	seen := map[[2]int]*expenseItem{}
+	for _, p := range pp {
+		c := p.commodity
+		if c.entryType() != EXPENSE {
+			continue
+		}
+		key := [2]int{p.row, p.item}
+		if item, ok := seen[key]; ok && p.row > 0 {
+			item.price += c.price
+			item.amount += c.amount
+			item.quantity += c.quantity
+			continue
+		}
+		item := &expenseItem{purchase: p, price: c.price, amount: c.amount, quantity: c.quantity}
+		seen[key] = item
+		items = append(items, item)
+	}
+	return items
+}
+
+// Typical price of a group of items, estimated with median and median absolute deviation
+type priceRange struct {
+	median, spread float64
+}
+
+func newPriceRange(values []float64) priceRange {
+	m := median(values)
+	var deviations []float64
+	var sum float64
+	for _, v := range values {
+		deviations = append(deviations, math.Abs(v-m))
+		sum += math.Abs(v - m)
+	}
+	// MAD scaled to standard deviation of normal distribution,
+	// mean absolute deviation if most values are the same
+	spread := 1.4826 * median(deviations)
+	if spread == 0 {
+		spread = 1.2533 * sum / float64(len(values))
+	}
+	return priceRange{m, math.Max(spread, m*ANOMALY_MIN_SPREAD)}
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Bounds of typical values, low bound isn't negative
+func (r priceRange) bounds(threshold float64) (float64, float64) {
+	return math.Max(r.median-threshold*r.spread, 0), r.median + threshold*r.spread
+}
+
+type anomaly struct {
+	item      *expenseItem
+	low, high float64
+	by        string // "name" or "category" the range is learnt from
+}
+
+// Find expenses with prices out of the typical range of the same name, or
+// of the category if there are not enough purchases with the name.
+// Items of different units are never compared.
+func (pp Purchases) anomalies(threshold float64, minSamples int) []*anomaly {
+	items := pp.expenseItems()
+
+	names := map[priceItem][]float64{}
+	categories := map[priceItem][]float64{}
+	nameKey := func(i *expenseItem) priceItem {
+		return priceItem{normalizeName(i.purchase.commodity.name), i.purchase.commodity.unit}
+	}
+	categoryKey := func(i *expenseItem) priceItem {
+		return priceItem{i.purchase.commodity.category, i.purchase.commodity.unit}
+	}
+	for _, i := range items {
+		names[nameKey(i)] = append(names[nameKey(i)], i.value())
+		categories[categoryKey(i)] = append(categories[categoryKey(i)], i.value())
+	}
+
+	ranges := map[string]map[priceItem]priceRange{"name": {}, "category": {}}
+	var result []*anomaly
+	for _, i := range items {
+		by, key, values := "name", nameKey(i), names[nameKey(i)]
+		if len(values) < minSamples {
+			by, key, values = "category", categoryKey(i), categories[categoryKey(i)]
+		}
+		if len(values) < minSamples {
+			continue
+		}
+		r, ok := ranges[by][key]
+		if !ok {
+			r = newPriceRange(values)
+			ranges[by][key] = r
+		}
+		low, high := r.bounds(threshold)
+		if v := i.value(); v < low || v > high {
+			result = append(result, &anomaly{i, low, high, by})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].item.purchase.row < result[j].item.purchase.row
+	})
+	return result
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// Print expenses with untypical prices, along with the expected range
+func anomaliesCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var threshold float64
+	var minSamples int
+	var output string
+	fs := newFlagSet("anomalies")
+	filter.register(fs)
+	fs.Float64Var(&threshold, "threshold", ANOMALY_THRESHOLD, "Modified z-score above which a price is an outlier")
+	fs.IntVar(&minSamples, "min-samples", ANOMALY_MIN_SAMPLES, "Minimum number of purchases to learn a typical price from")
+	fs.StringVar(&output, "output", "table", "Output format: table, csv, json or md")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if threshold <= 0 || minSamples < 2 {
+		return fmt.Errorf("threshold must be positive and min-samples must be at least 2")
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+
+	t := &table{name: "anomalies", columns: []column{
+		{"Row", kindInt},
+		{"Date", kindDate},
+		{"Item", kindString},
+		{"Price", kindDecimal},
+		{"Unit", kindString},
+		{"Low", kindDecimal},
+		{"High", kindDecimal},
+		{"By", kindString},
+	}}
+	for _, a := range pp.anomalies(threshold, minSamples) {
+		p := a.item.purchase
+		t.rows = append(t.rows, []string{
+			strconv.Itoa(p.row),
+			p.date.Format(df),
+			p.source,
+			formatAmount(a.item.value()),
+			p.commodity.unit,
+			formatAmount(a.low),
+			formatAmount(a.high),
+			a.by,
+		})
+	}
+	return t.writeFormat(w, output)
+}
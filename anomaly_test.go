@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func anomalyTestPurchases(t *testing.T) Purchases {
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.01.2024", "кофе (200), продукты - хлеб (50)"},
+		{"02.01.2024", "кофе (180), продукты - сыр (150)"},
+		{"03.01.2024", "кофе (220), продукты - молоко (90)"},
+		{"04.01.2024", "кофе (210), Маша+Петя/кофе (2000)"},
+		{"05.01.2024", "кофе (190), бензин 40л (2200), продукты - хлеб (60)"},
+		{"06.01.2024", "кофе (200), бензин 30л (1680), продукты - икра (5000)"},
+		{"07.01.2024", "бензин 45л (2500), бензин 40л (22000), бензин 35л (1950), возврат (-5000)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	return purchases
+}
+
+func TestExpenseItems(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{{"Date", "Items"}}
+	for range 5 {
+		records = append(records, []string{"01.01.2024", "кофе (200)"})
+	}
+	records = append(records, []string{"02.01.2024", "кофе (200), кофе (200), Маша+Петя/кофе (200)"})
+	pp, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	items := pp.expenseItems()
+	assert.Len(t, items, 8, "duplicate items in a row are separate items")
+	for _, i := range items {
+		assert.Equal(t, 200, i.price)
+	}
+	assert.Equal(t, 3, items[7].purchase.item)
+	assert.Empty(t, pp.anomalies(ANOMALY_THRESHOLD, ANOMALY_MIN_SAMPLES))
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 2.0, median([]float64{3, 1, 2}))
+	assert.Equal(t, 2.5, median([]float64{4, 1, 3, 2}))
+}
+
+func TestPriceRange(t *testing.T) {
+	r := newPriceRange([]float64{200, 150, 250, 210, 2000})
+	assert.Equal(t, 210.0, r.median)
+	assert.InDelta(t, 1.4826*40, r.spread, 1e-9, "outliers don't widen the range")
+	r = newPriceRange([]float64{50, 50, 50, 50, 55})
+	assert.InDelta(t, 5, r.spread, 1e-9, "at least a tenth of median")
+	low, high := newPriceRange([]float64{10, 10, 10, 10, 100}).bounds(3.5)
+	assert.InDelta(t, 0, low, 1e-9)
+	assert.InDelta(t, 10+3.5*1.2533*18, high, 1e-9, "mean absolute deviation when most values are the same")
+}
+
+func TestAnomalies(t *testing.T) {
+	saveGlobals(t)
+	anomalies := anomalyTestPurchases(t).anomalies(ANOMALY_THRESHOLD, ANOMALY_MIN_SAMPLES)
+	assert.Len(t, anomalies, 3)
+
+	coffee := anomalies[0]
+	assert.Equal(t, 5, coffee.item.purchase.row)
+	assert.Equal(t, "Маша+Петя/кофе (2000)", coffee.item.purchase.source)
+	assert.Equal(t, 2000, coffee.item.price, "shared purchase is joined back")
+	assert.Equal(t, "name", coffee.by)
+
+	caviar := anomalies[1]
+	assert.Equal(t, "продукты - икра (5000)", caviar.item.purchase.source)
+	assert.Equal(t, "category", caviar.by)
+
+	fuel := anomalies[2]
+	assert.Equal(t, 550.0, fuel.item.value(), "unit prices are compared")
+
+	assert.Empty(t, anomalyTestPurchases(t).anomalies(ANOMALY_THRESHOLD, 10))
+}
+
+func TestAnomaliesCommand(t *testing.T) {
+	saveGlobals(t)
+	var buf bytes.Buffer
+	assert.NoError(t, anomaliesCommand(&buf, anomalyTestPurchases(t), nil))
+	expected := `Row  Date        Item                    Price  Unit  Low    High    By
+5    04.01.2024  Маша+Петя/кофе (2000)   2000         130    270     name
+7    06.01.2024  продукты - икра (5000)  5000         0      297.56  category
+8    07.01.2024  бензин 40л (22000)      550    л     36.21  75.21   name
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, anomaliesCommand(&buf, anomalyTestPurchases(t), []string{"-to", "05.01.2024", "-output", "csv"}))
+	assert.Equal(t, "Row,Date,Item,Price,Unit,Low,High,By\n5,04.01.2024,Маша+Петя/кофе (2000),2000,,127.16,282.84,name\n", buf.String())
+
+	assert.Error(t, anomaliesCommand(&buf, anomalyTestPurchases(t), []string{"-threshold", "0"}))
+	assert.Error(t, anomaliesCommand(&buf, anomalyTestPurchases(t), []string{"-min-samples", "1"}))
+}
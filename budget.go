@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spending limit from config, limits are in roubles per period.
+// Category is the one after replacements, or a category path like
+// "еда:кафе" matching the subcategory and subcategories below it.
+// Empty category or person matches all of them. With rollover unspent
+// amount of previous periods is added to the limit, and overspending
+// is subtracted from it.
+type Budget struct {
+	Name     string `yaml:"name"`
+	Category string `yaml:"category"`
+	Person   string `yaml:"person"`
+	Limit    int    `yaml:"limit"`
+	Period   string `yaml:"period"`
+	Rollover bool   `yaml:"rollover"`
+}
+
+// Budgets checked by budget command
+var budgets []Budget
+
+// Validate budgets, lowercase category and person, and fill in
+// default names and periods
+func checkBudgets(bb []Budget) ([]Budget, error) {
+	var result []Budget
+	for i, b := range bb {
+		var path []string
+		for _, segment := range strings.Split(b.Category, ":") {
+			if segment = strings.ToLower(strings.TrimSpace(segment)); segment != "" {
+				path = append(path, segment)
+			}
+		}
+		b.Category = strings.Join(path, ":")
+		b.Person = strings.ToLower(strings.TrimSpace(b.Person))
+		if person, ok := personAliases[b.Person]; ok {
+			b.Person = person
+		}
+		if b.Name == "" {
+			b.Name = strings.Trim(b.Person+"/"+b.Category, "/")
+		}
+		if b.Name == "" {
+			b.Name = "all"
+		}
+		if b.Period == "" {
+			b.Period = "month"
+		}
+		if b.Period != "month" && b.Period != "year" {
+			return nil, fmt.Errorf("budget %d: unknown period: %s", i+1, b.Period)
+		}
+		if b.Limit <= 0 {
+			return nil, fmt.Errorf("budget %d: limit must be positive", i+1)
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// Load budgets from YAML file with "budgets" list in the same format as in config
+func loadBudgets(path string) ([]Budget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return checkBudgets(cfg.Budgets)
+}
+
+// Bounds of the budget period containing the date, end is exclusive
+func (b *Budget) bounds(d time.Time) (time.Time, time.Time) {
+	if b.Period == "year" {
+		start := time.Date(d.Year(), 1, 1, 0, 0, 0, 0, d.Location())
+		return start, start.AddDate(1, 0, 0)
+	}
+	start := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+func (b *Budget) matches(c *Commodity) bool {
+	path := c.category
+	if c.subcategory != "" {
+		path += ":" + c.subcategory
+	}
+	category := b.Category == "" || path == b.Category || strings.HasPrefix(path, b.Category+":")
+	return category && (b.Person == "" || b.Person == c.person)
+}
+
+type budgetStatus struct {
+	budget    *Budget
+	period    time.Time
+	limit     int // including rollover
+	spent     int
+	projected int // spending at the end of the period at the current pace
+}
+
+func (s *budgetStatus) remaining() int {
+	return s.limit - s.spent
+}
+
+// Period as YYYY-MM month or YYYY year
+func (s *budgetStatus) periodName() string {
+	if s.budget.Period == "year" {
+		return s.period.Format("2006")
+	}
+	return s.period.Format("2006-01")
+}
+
+func (s *budgetStatus) status() string {
+	switch {
+	case s.spent > s.limit:
+		return "exceeded"
+	case s.projected > s.limit:
+		return "warning"
+	}
+	return "ok"
+}
+
+// Budget status for the period containing the date, purchases after the date
+// are skipped. Shared purchases are already split into shares of persons by
+// getPurchases, so a person budget gets the person's share only.
+// Rollover starts from the first period with matching purchases.
+func (pp Purchases) budgetStatus(b *Budget, date time.Time) *budgetStatus {
+	start, end := b.bounds(date)
+	spent := map[time.Time]int{}
+	first := start
+	for _, p := range pp.expenses() {
+		if p.date.After(date) {
+			continue
+		}
+		if !b.matches(p.commodity) {
+			continue
+		}
+		period, _ := b.bounds(p.date)
+		spent[period] += p.commodity.price
+		if period.Before(first) {
+			first = period
+		}
+	}
+
+	s := &budgetStatus{budget: b, period: start, limit: b.Limit, spent: spent[start]}
+	if b.Rollover {
+		for period := first; period.Before(start); {
+			s.limit += b.Limit - spent[period]
+			_, period = b.bounds(period)
+		}
+	}
+	elapsed := math.Floor(date.Sub(start).Hours()/24) + 1
+	total := math.Round(end.Sub(start).Hours() / 24)
+	s.projected = int(math.Round(float64(s.spent) * total / elapsed))
+	return s
+}
+
+// Print budget limits, spending and projected spending for the period
+// containing the date. Fails if any limit is exceeded, or is projected
+// to be exceeded with -strict.
+func budgetCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var path, date string
+	var strict bool
+	fs := newFlagSet("budget")
+	filter.register(fs)
+	fs.StringVar(&path, "file", "", "Budgets file, budgets from config by default")
+	fs.StringVar(&date, "date", "", "Date to check budgets on in -df format, today by default")
+	fs.BoolVar(&strict, "strict", false, "Fail on projected overspending too")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+	bb := budgets
+	if path != "" {
+		if bb, err = loadBudgets(path); err != nil {
+			return err
+		}
+	}
+	if len(bb) == 0 {
+		return fmt.Errorf("no budgets")
+	}
+	day := now()
+	if date != "" {
+		if day, err = time.Parse(df, date); err != nil {
+			return err
+		}
+	}
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	t := &table{columns: []column{
+		{"Budget", kindString},
+		{"Period", kindString},
+		{"Limit", kindInt},
+		{"Spent", kindInt},
+		{"Remaining", kindInt},
+		{"Projected", kindInt},
+		{"Status", kindString},
+	}}
+	var failed []string
+	for i := range bb {
+		s := pp.budgetStatus(&bb[i], day)
+		t.rows = append(t.rows, []string{
+			bb[i].Name,
+			s.periodName(),
+			strconv.Itoa(s.limit),
+			strconv.Itoa(s.spent),
+			strconv.Itoa(s.remaining()),
+			strconv.Itoa(s.projected),
+			s.status(),
+		})
+		if status := s.status(); status == "exceeded" || strict && status == "warning" {
+			failed = append(failed, bb[i].Name)
+		}
+	}
+	if err := t.writeText(w); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("budgets over limit: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func budgetTestPurchases(t *testing.T) Purchases {
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"10.01.2024", "продукты - хлеб (3000), Маша/кафе (500)"},
+		{"05.02.2024", "продукты - сыр (6000), Маша+Петя/кафе (1000)"},
+		{"10.03.2024", "продукты - сыр (2000), +зарплата (100000), продукты - возврат (-500)"},
+		{"20.03.2024", "продукты - мясо (9000)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	return purchases
+}
+
+func TestCheckBudgets(t *testing.T) {
+	saveGlobals(t)
+	personAliases["мария"] = "маша"
+	bb, err := checkBudgets([]Budget{
+		{Category: "Продукты", Limit: 5000},
+		{Person: "Мария", Limit: 1000, Period: "year"},
+		{Limit: 20000},
+		{Category: "Еда : Кафе", Limit: 3000},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Budget{
+		{Name: "продукты", Category: "продукты", Limit: 5000, Period: "month"},
+		{Name: "маша", Person: "маша", Limit: 1000, Period: "year"},
+		{Name: "all", Limit: 20000, Period: "month"},
+		{Name: "еда:кафе", Category: "еда:кафе", Limit: 3000, Period: "month"},
+	}, bb)
+
+	_, err = checkBudgets([]Budget{{Limit: 100, Period: "week"}})
+	assert.EqualError(t, err, "budget 1: unknown period: week")
+	_, err = checkBudgets([]Budget{{Category: "кафе"}})
+	assert.EqualError(t, err, "budget 1: limit must be positive")
+}
+
+func TestBudgetMatches(t *testing.T) {
+	cafe := &Commodity{person: "маша", category: "еда", subcategory: "кафе:кофе"}
+	food := &Commodity{person: "маша", category: "еда"}
+	cafeteria := &Commodity{person: "маша", category: "еда", subcategory: "кафетерий"}
+
+	for _, tt := range []struct {
+		category string
+		matches  []bool
+	}{
+		{"", []bool{true, true, true}},
+		{"еда", []bool{true, true, true}},
+		{"еда:кафе", []bool{true, false, false}},
+		{"еда:кафе:кофе", []bool{true, false, false}},
+		{"кафе", []bool{false, false, false}},
+	} {
+		b := &Budget{Category: tt.category}
+		assert.Equal(t, tt.matches, []bool{b.matches(cafe), b.matches(food), b.matches(cafeteria)}, tt.category)
+	}
+	assert.False(t, (&Budget{Person: "петя"}).matches(cafe))
+}
+
+func TestBudgetStatus(t *testing.T) {
+	saveGlobals(t)
+	pp := budgetTestPurchases(t)
+	march := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		budget    Budget
+		date      time.Time
+		limit     int
+		spent     int
+		projected int
+		status    string
+	}{
+		{"category", Budget{Category: "продукты", Limit: 5000, Period: "month"}, march, 5000, 1500, 4650, "ok"},
+		{"later purchases are skipped", Budget{Category: "продукты", Limit: 5000, Period: "month"}, march.AddDate(0, 0, 10), 5000, 10500, 16275, "exceeded"},
+		{"rollover", Budget{Category: "продукты", Limit: 5000, Period: "month", Rollover: true}, march, 6000, 1500, 4650, "ok"},
+		{"projected", Budget{Category: "продукты", Limit: 4000, Period: "month"}, march, 4000, 1500, 4650, "warning"},
+		{"person shares", Budget{Person: "маша", Limit: 1000, Period: "year"}, march, 1000, 1000, 5229, "warning"},
+		{"no purchases", Budget{Category: "авто", Limit: 1000, Period: "month"}, march, 1000, 0, 0, "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := pp.budgetStatus(&tt.budget, tt.date)
+			assert.Equal(t, tt.limit, s.limit)
+			assert.Equal(t, tt.spent, s.spent)
+			assert.Equal(t, tt.projected, s.projected)
+			assert.Equal(t, tt.status, s.status())
+		})
+	}
+}
+
+func TestBudgetCommand(t *testing.T) {
+	saveGlobals(t)
+	budgets = []Budget{
+		{Name: "продукты", Category: "продукты", Limit: 5000, Period: "month", Rollover: true},
+		{Name: "кафе", Category: "кафе", Limit: 5000, Period: "year"},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, budgetCommand(&buf, budgetTestPurchases(t), []string{"-date", "10.03.2024"}))
+	expected := `Budget    Period   Limit  Spent  Remaining  Projected  Status
+продукты  2024-03  6000   1500   4500       4650       ok
+кафе      2024     5000   1500   3500       7843       warning
+`
+	assert.Equal(t, expected, buf.String())
+
+	err := budgetCommand(&bytes.Buffer{}, budgetTestPurchases(t), []string{"-date", "10.03.2024", "-strict"})
+	assert.EqualError(t, err, "budgets over limit: кафе")
+
+	buf.Reset()
+	err = budgetCommand(&buf, budgetTestPurchases(t), []string{"-date", "31.03.2024"})
+	assert.EqualError(t, err, "budgets over limit: продукты")
+	assert.Contains(t, buf.String(), "продукты  2024-03  6000   10500  -4500      10500      exceeded\n")
+
+	path := filepath.Join(t.TempDir(), "budgets.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("budgets:\n  - category: кафе\n    limit: 300\n"), 0644))
+	buf.Reset()
+	err = budgetCommand(&buf, budgetTestPurchases(t), []string{"-date", "29.02.2024", "-file", path})
+	assert.EqualError(t, err, "budgets over limit: кафе")
+	assert.Contains(t, buf.String(), "кафе    2024-02  300    1000   -700       1000       exceeded\n")
+
+	budgets = nil
+	assert.EqualError(t, budgetCommand(&bytes.Buffer{}, budgetTestPurchases(t), nil), "no budgets")
+}
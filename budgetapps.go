@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Date layouts expected by budgeting apps importers
+const (
+	ynabDateFormat    = "01/02/2006"
+	fireflyDateFormat = "2006-01-02"
+	actualDateFormat  = "2006-01-02"
+)
+
+// Parse "person=account,person=account" mapping table
+func parseMapping(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid mapping: %s", item)
+		}
+		m[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return m, nil
+}
+
+// Account of a person from mapping table, the person itself if not mapped
+func personAccount(accounts map[string]string, person string) string {
+	if a, ok := accounts[person]; ok {
+		return a
+	}
+	return person
+}
+
+// Account the purchase is marked with, the account of its person otherwise
+func purchaseAccount(accounts map[string]string, c *Commodity) string {
+	if c.account != "" {
+		return c.account
+	}
+	return personAccount(accounts, c.person)
+}
+
+func formatMoney(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+// YNAB file import layout: outflow and inflow are separate positive columns,
+// there's no account column so the account goes to memo. Transfers are skipped
+// by all budgeting apps layouts.
+func (pp Purchases) toYnab(accounts map[string]string) *table {
+	t := &table{
+		name: "ynab",
+		columns: []column{
+			{"Date", kindString},
+			{"Payee", kindString},
+			{"Memo", kindString},
+			{"Outflow", kindDecimal},
+			{"Inflow", kindDecimal},
+		},
+	}
+	for _, p := range pp {
+		c := p.commodity
+		if c.isTransfer() {
+			continue
+		}
+		var outflow, inflow string
+		if flow := c.flow(); flow < 0 {
+			outflow = formatMoney(-float64(flow))
+		} else {
+			inflow = formatMoney(float64(flow))
+		}
+		t.rows = append(t.rows, []string{
+			p.date.Format(ynabDateFormat),
+			c.name,
+			fmt.Sprintf("[%s] %s", purchaseAccount(accounts, c), c.category),
+			outflow,
+			inflow,
+		})
+	}
+	return t
+}
+
+// Firefly III data importer layout: withdrawals have negative amount,
+// source is the asset account and destination is the payee,
+// deposits have positive amount and the other way round
+func (pp Purchases) toFirefly(accounts map[string]string) *table {
+	t := &table{
+		name: "firefly",
+		columns: []column{
+			{"date", kindString},
+			{"description", kindString},
+			{"amount", kindDecimal},
+			{"currency_code", kindString},
+			{"source_name", kindString},
+			{"destination_name", kindString},
+			{"category_name", kindString},
+			{"tags", kindString},
+		},
+	}
+	for _, p := range pp {
+		c := p.commodity
+		if c.isTransfer() {
+			continue
+		}
+		source, destination := purchaseAccount(accounts, c), c.name
+		if c.flow() > 0 {
+			source, destination = destination, source
+		}
+		t.rows = append(t.rows, []string{
+			p.date.Format(fireflyDateFormat),
+			c.name,
+			formatMoney(float64(c.flow())),
+			DEFAULT_CURRENCY,
+			source,
+			destination,
+			c.category,
+			c.person,
+		})
+	}
+	return t
+}
+
+// Actual Budget CSV import layout: outflows are negative amounts, inflows are positive
+func (pp Purchases) toActual(accounts map[string]string) *table {
+	t := &table{
+		name: "actual",
+		columns: []column{
+			{"Date", kindString},
+			{"Payee", kindString},
+			{"Notes", kindString},
+			{"Category", kindString},
+			{"Amount", kindDecimal},
+			{"Account", kindString},
+		},
+	}
+	for _, p := range pp {
+		c := p.commodity
+		if c.isTransfer() {
+			continue
+		}
+		t.rows = append(t.rows, []string{
+			p.date.Format(actualDateFormat),
+			c.name,
+			c.person,
+			c.category,
+			formatMoney(float64(c.flow())),
+			purchaseAccount(accounts, c),
+		})
+	}
+	return t
+}
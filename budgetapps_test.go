@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func budgetAppsTestPurchases() Purchases {
+	return Purchases{
+		{
+			date:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "маша", category: "кафе", name: "кофе", price: 200},
+		},
+		{
+			date:      time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "общие", category: "продукты", name: "хлеб", price: 50},
+		},
+		{
+			date:      time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "общие", category: "продукты", name: "хлеб", price: -50},
+		},
+		{
+			date:      time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "маша", category: "зарплата", name: "зарплата", price: 100000, income: true},
+		},
+		{
+			date:      time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "маша", category: TRANSFER_CATEGORY, name: TRANSFER_CATEGORY, price: 5000, account: "карта", toAccount: "наличные"},
+		},
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	m, err := parseMapping("Маша=Карта Маши, общие = Наличные,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"маша": "Карта Маши", "общие": "Наличные"}, m)
+
+	m, err = parseMapping("")
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+
+	_, err = parseMapping("маша")
+	assert.Error(t, err)
+	_, err = parseMapping("=Наличные")
+	assert.Error(t, err)
+}
+
+func TestToYnab(t *testing.T) {
+	var buf bytes.Buffer
+	accounts := map[string]string{"маша": "Карта Маши"}
+	assert.NoError(t, budgetAppsTestPurchases().toYnab(accounts).writeCsv(&buf))
+	expected := `Date,Payee,Memo,Outflow,Inflow
+03/01/2024,кофе,[Карта Маши] кафе,200.00,
+03/02/2024,хлеб,[общие] продукты,50.00,
+03/03/2024,хлеб,[общие] продукты,,50.00
+03/05/2024,зарплата,[Карта Маши] зарплата,,100000.00
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestToFirefly(t *testing.T) {
+	var buf bytes.Buffer
+	accounts := map[string]string{"маша": "Карта Маши", "общие": "Наличные"}
+	assert.NoError(t, budgetAppsTestPurchases().toFirefly(accounts).writeCsv(&buf))
+	expected := `date,description,amount,currency_code,source_name,destination_name,category_name,tags
+2024-03-01,кофе,-200.00,RUB,Карта Маши,кофе,кафе,маша
+2024-03-02,хлеб,-50.00,RUB,Наличные,хлеб,продукты,общие
+2024-03-03,хлеб,50.00,RUB,хлеб,Наличные,продукты,общие
+2024-03-05,зарплата,100000.00,RUB,зарплата,Карта Маши,зарплата,маша
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestToActual(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, budgetAppsTestPurchases().toActual(nil).writeCsv(&buf))
+	expected := `Date,Payee,Notes,Category,Amount,Account
+2024-03-01,кофе,маша,кафе,-200.00,маша
+2024-03-02,хлеб,общие,продукты,-50.00,общие
+2024-03-03,хлеб,общие,продукты,50.00,общие
+2024-03-05,зарплата,маша,зарплата,100000.00,маша
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestPurchaseAccount(t *testing.T) {
+	accounts := map[string]string{"маша": "Карта Маши"}
+	assert.Equal(t, "Карта Маши", purchaseAccount(accounts, &Commodity{person: "маша"}))
+	assert.Equal(t, "наличные", purchaseAccount(accounts, &Commodity{person: "маша", account: "наличные"}))
+	assert.Equal(t, "общие", purchaseAccount(accounts, &Commodity{person: "общие"}))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Type of an entry, see Commodity.entryType
+type entryType string
+
+const (
+	EXPENSE  entryType = "expense"
+	INCOME   entryType = "income"
+	REFUND   entryType = "refund"
+	TRANSFER entryType = "transfer"
+)
+
+// Categories of income entries in addition to ones marked with "+"
+var incomeCategories = map[string]bool{}
+
+// Refund is an expense with negative price
+func (c *Commodity) entryType() entryType {
+	switch {
+	case c.isTransfer():
+		return TRANSFER
+	case c.income:
+		return INCOME
+	case c.price < 0:
+		return REFUND
+	}
+	return EXPENSE
+}
+
+// Expenses and refunds are spending, income and transfers are not
+func (c *Commodity) isExpense() bool {
+	return !c.isTransfer() && !c.income
+}
+
+// Change of the entry account balance: income comes in,
+// expenses and transfers go out, refunds come back
+func (c *Commodity) flow() int {
+	if c.income {
+		return c.price
+	}
+	return -c.price
+}
+
+type cashFlow struct {
+	month            time.Time
+	person           string
+	income, expenses int
+}
+
+func (f cashFlow) savings() int {
+	return f.income - f.expenses
+}
+
+// Percentage of income saved, false if there's no income
+func (f cashFlow) savingsRate() (float64, bool) {
+	if f.income <= 0 {
+		return 0, false
+	}
+	return float64(f.savings()) * 100 / float64(f.income), true
+}
+
+// Income and expenses per month and person, transfers are skipped
+func (pp Purchases) cashFlows() []cashFlow {
+	flows := map[string]*cashFlow{}
+	for _, p := range pp {
+		c := p.commodity
+		if c.isTransfer() {
+			continue
+		}
+		month, _ := periodStart(p.date, "month")
+		key := fmt.Sprintf("%d|%s", month.Unix(), c.person)
+		f, ok := flows[key]
+		if !ok {
+			f = &cashFlow{month: month, person: c.person}
+			flows[key] = f
+		}
+		if c.income {
+			f.income += c.price
+		} else {
+			f.expenses += c.price
+		}
+	}
+
+	var result []cashFlow
+	for _, f := range flows {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].month.Equal(result[j].month) {
+			return result[i].month.Before(result[j].month)
+		}
+		return result[i].person < result[j].person
+	})
+	return result
+}
+
+// Print monthly income, expenses and savings rate per person
+func cashflowCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	fs := newFlagSet("cashflow")
+	filter.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+
+	t := &table{columns: []column{
+		{"Month", kindString},
+		{"Person", kindString},
+		{"Income", kindDecimal},
+		{"Expenses", kindDecimal},
+		{"Savings", kindDecimal},
+		{"Rate", kindString},
+	}}
+	for _, f := range pp.cashFlows() {
+		var rate string
+		if r, ok := f.savingsRate(); ok {
+			rate = fmt.Sprintf("%.1f%%", r)
+		}
+		t.rows = append(t.rows, []string{
+			f.month.Format("2006-01"),
+			f.person,
+			strconv.Itoa(f.income),
+			strconv.Itoa(f.expenses),
+			strconv.Itoa(f.savings()),
+			rate,
+		})
+	}
+	return t.writeText(w)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryType(t *testing.T) {
+	saveGlobals(t)
+	incomeCategories["кэшбэк"] = true
+
+	tests := []struct {
+		input    string
+		expected entryType
+		price    int
+		flow     int
+	}{
+		{"хлеб (50)", EXPENSE, 50, -50},
+		{"+Маша/зарплата (100000)", INCOME, 100000, 100000},
+		{"+ подработка (5000) [карта]", INCOME, 5000, 5000},
+		{"кэшбэк (300)", INCOME, 300, 300},
+		{"одежда - возврат (-1500)", REFUND, -1500, 1500},
+		{"[карта>наличные] (5000)", TRANSFER, 5000, -5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := newCommodity(tt.input, time.Time{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, c.entryType())
+			assert.Equal(t, tt.price, c.price)
+			assert.Equal(t, tt.flow, c.flow())
+			assert.Equal(t, tt.expected == EXPENSE || tt.expected == REFUND, c.isExpense())
+		})
+	}
+
+	c, err := newCommodity("+Маша+Петя/подарок (1000)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "маша+петя", c.person)
+	assert.Equal(t, INCOME, c.entryType())
+}
+
+func TestCashFlows(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.03.2024", "+Маша/зарплата (100000), Маша/кафе (2000), хлеб (50)"},
+		{"10.03.2024", "Маша/одежда (5000), Маша/одежда - возврат (-1000), [карта>наличные] (5000)"},
+		{"01.04.2024", "Маша/кафе (1000)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	flows := purchases.cashFlows()
+	assert.Equal(t, []cashFlow{
+		{march, "маша", 100000, 6000},
+		{march, "общие", 0, 50},
+		{april, "маша", 0, 1000},
+	}, flows)
+
+	rate, ok := flows[0].savingsRate()
+	assert.True(t, ok)
+	assert.Equal(t, 94.0, rate)
+	_, ok = flows[1].savingsRate()
+	assert.False(t, ok)
+}
+
+func TestCashflowCommand(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.03.2024", "+Маша/зарплата (100000), Маша/кафе (2000), хлеб (50)"},
+		{"01.04.2024", "Маша/кафе (1000)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cashflowCommand(&buf, purchases, []string{"-to", "31.03.2024"}))
+	expected := `Month    Person  Income  Expenses  Savings  Rate
+2024-03  маша    100000  2000      98000    98.0%
+2024-03  общие   0       50        -50
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Subcommand working on parsed purchases, args are the ones after command name
+type command func(w io.Writer, pp Purchases, args []string) error
+
+var commands = map[string]command{
+	"settle":    settleCommand,
+	"balance":   balanceCommand,
+	"cashflow":  cashflowCommand,
+	"prices":    pricesCommand,
+	"report":    reportCommand,
+	"budget":    budgetCommand,
+	"compare":   compareCommand,
+	"anomalies": anomaliesCommand,
+	"forecast":  forecastCommand,
+	"recurring": recurringCommand,
+}
+
+// Filter of purchases common for subcommands, with optional
+// restatement of prices in constant roubles
+type purchaseFilter struct {
+	from, to, tags string
+	real           bool
+}
+
+func (f *purchaseFilter) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.from, "from", "", "First date of the range, inclusive, in -df format")
+	fs.StringVar(&f.to, "to", "", "Last date of the range, inclusive, in -df format")
+	fs.StringVar(&f.tags, "tag", "", "Comma separated tags, purchases with any of them are kept")
+	fs.BoolVar(&f.real, "real", false, "Restate prices in constant roubles of -cpi-base month")
+}
+
+func (f *purchaseFilter) apply(pp Purchases) (Purchases, error) {
+	var from, to time.Time
+	var err error
+	if f.from != "" {
+		if from, err = time.Parse(df, f.from); err != nil {
+			return nil, err
+		}
+	}
+	if f.to != "" {
+		if to, err = time.Parse(df, f.to); err != nil {
+			return nil, err
+		}
+	}
+	tags := parseTags(f.tags)
+	var result Purchases
+	for _, p := range pp {
+		if !from.IsZero() && p.date.Before(from) {
+			continue
+		}
+		if !to.IsZero() && p.date.After(to) {
+			continue
+		}
+		if !p.commodity.hasAnyTag(tags) {
+			continue
+		}
+		result = append(result, p)
+	}
+	if f.real {
+		return result.real()
+	}
+	return result, nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: finparser [options] %s [%s options] < input.csv\n", name, name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expenses of a group in base and current periods
+type comparison struct {
+	keys          []string
+	base, current int
+}
+
+func (c *comparison) diff() int {
+	return c.current - c.base
+}
+
+// Change in percents, false if there were no expenses in the base period
+func (c *comparison) change() (float64, bool) {
+	if c.base == 0 {
+		return 0, false
+	}
+	return float64(c.diff()) * 100 / float64(c.base), true
+}
+
+// "new" for groups missing in the base period, "gone" for groups missing in the current one
+func (c *comparison) status() string {
+	switch {
+	case c.base == 0 && c.current != 0:
+		return "new"
+	case c.base != 0 && c.current == 0:
+		return "gone"
+	}
+	return ""
+}
+
+// Compare expenses aggregated by dimensions, groups are sorted by keys
+func compareExpenses(base, current Purchases, by []string) ([]*comparison, error) {
+	groups := map[string]*comparison{}
+	get := func(keys []string) *comparison {
+		id := strings.Join(keys, "\x00")
+		c, ok := groups[id]
+		if !ok {
+			c = &comparison{keys: keys}
+			groups[id] = c
+		}
+		return c
+	}
+	baseGroups, err := base.report(by)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range baseGroups {
+		get(g.keys).base = g.total
+	}
+	currentGroups, err := current.report(by)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range currentGroups {
+		get(g.keys).current = g.total
+	}
+
+	var result []*comparison
+	for _, id := range sortedKeys(groups) {
+		result = append(result, groups[id])
+	}
+	return result, nil
+}
+
+// Read and parse purchases from CSV file, any parse error fails
+func readPurchases(path string) (Purchases, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(bufio.NewReader(f)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	purchases, errors := getPurchases(records)
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("%s: %d parse errors, the first is: %s", path, len(errors), errors[0])
+	}
+	return purchases, nil
+}
+
+// Print expenses of the base and current periods with differences per group,
+// and the total of all groups
+func compareCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var baseFrom, baseTo, baseFile, by, output, order string
+	fs := newFlagSet("compare")
+	filter.register(fs)
+	fs.StringVar(&baseFrom, "base-from", "", "First date of the base range, inclusive, in -df format")
+	fs.StringVar(&baseTo, "base-to", "", "Last date of the base range, inclusive, in -df format")
+	fs.StringVar(&baseFile, "base-file", "", "Input CSV file with base purchases, the same input by default")
+	fs.StringVar(&by, "by", "category", "Comma separated dimensions: day, week, month, quarter, year, person, category or name")
+	fs.StringVar(&output, "output", "table", "Output format: table, csv, json or md")
+	fs.StringVar(&order, "sort", "key", "Sort groups by key, or by absolute difference descending")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if baseFrom == "" && baseTo == "" && baseFile == "" {
+		return fmt.Errorf("base range or base file is required")
+	}
+	// Both periods come from the same input, so they must not overlap: the
+	// current one starts the day after the base one by default, and the base
+	// one ends the day before the current one
+	if baseFile == "" {
+		if filter.from == "" && baseTo == "" {
+			return fmt.Errorf("-from or -base-to is required without base file")
+		}
+		if filter.from == "" {
+			to, err := time.Parse(df, baseTo)
+			if err != nil {
+				return err
+			}
+			filter.from = to.AddDate(0, 0, 1).Format(df)
+		}
+		from, err := time.Parse(df, filter.from)
+		if err != nil {
+			return err
+		}
+		if baseTo == "" {
+			baseTo = from.AddDate(0, 0, -1).Format(df)
+		}
+		to, err := time.Parse(df, baseTo)
+		if err != nil {
+			return err
+		}
+		if !to.Before(from) {
+			return fmt.Errorf("base period must end before the current one starts")
+		}
+	}
+
+	base := pp
+	var err error
+	if baseFile != "" {
+		if base, err = readPurchases(baseFile); err != nil {
+			return err
+		}
+	}
+	baseFilter := filter
+	baseFilter.from, baseFilter.to = baseFrom, baseTo
+	if base, err = baseFilter.apply(base); err != nil {
+		return err
+	}
+	current, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+
+	dimensions := splitList(by)
+	if len(dimensions) == 0 {
+		return fmt.Errorf("no dimensions to compare by")
+	}
+	comparisons, err := compareExpenses(base, current, dimensions)
+	if err != nil {
+		return err
+	}
+	switch order {
+	case "key":
+	case "diff":
+		abs := func(i int) int {
+			return max(comparisons[i].diff(), -comparisons[i].diff())
+		}
+		sort.SliceStable(comparisons, func(i, j int) bool {
+			return abs(i) > abs(j)
+		})
+	default:
+		return fmt.Errorf("unknown sort order: %s", order)
+	}
+
+	t := &table{name: "compare"}
+	for _, d := range dimensions {
+		t.columns = append(t.columns, column{strings.ToUpper(d[:1]) + d[1:], kindString})
+	}
+	t.columns = append(t.columns,
+		column{"Base", kindInt},
+		column{"Current", kindInt},
+		column{"Diff", kindInt},
+		column{"Change", kindString},
+		column{"Status", kindString},
+	)
+	total := &comparison{keys: make([]string, len(dimensions))}
+	total.keys[0] = "total"
+	for _, c := range comparisons {
+		total.base += c.base
+		total.current += c.current
+	}
+	for _, c := range append(comparisons, total) {
+		status := c.status()
+		if c == total {
+			status = ""
+		}
+		row := append([]string{}, c.keys...)
+		t.rows = append(t.rows, append(row,
+			strconv.Itoa(c.base),
+			strconv.Itoa(c.current),
+			strconv.Itoa(c.diff()),
+			formatChange(c.change()),
+			status,
+		))
+	}
+	return t.writeFormat(w, output)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func compareTestPurchases(t *testing.T) Purchases {
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"10.01.2024", "продукты - хлеб (1000), кафе (500), Маша/кино (300)"},
+		{"10.01.2025", "продукты - хлеб (1500), кафе (400), Маша/спорт (2000), +зарплата (50000)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	return purchases
+}
+
+func TestComparison(t *testing.T) {
+	c := &comparison{base: 1000, current: 1500}
+	assert.Equal(t, 500, c.diff())
+	change, ok := c.change()
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, change)
+	assert.Equal(t, "", c.status())
+
+	c = &comparison{current: 1500}
+	_, ok = c.change()
+	assert.False(t, ok)
+	assert.Equal(t, "new", c.status())
+	assert.Equal(t, "gone", (&comparison{base: 300}).status())
+}
+
+func TestCompareExpenses(t *testing.T) {
+	saveGlobals(t)
+	pp := compareTestPurchases(t)
+	base, err := (&purchaseFilter{to: "31.12.2024"}).apply(pp)
+	assert.NoError(t, err)
+	current, err := (&purchaseFilter{from: "01.01.2025"}).apply(pp)
+	assert.NoError(t, err)
+
+	comparisons, err := compareExpenses(base, current, []string{"person"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*comparison{
+		{keys: []string{"маша"}, base: 300, current: 2000},
+		{keys: []string{"общие"}, base: 1500, current: 1900},
+	}, comparisons)
+
+	_, err = compareExpenses(base, current, []string{"shop"})
+	assert.Error(t, err)
+}
+
+func TestCompareCommand(t *testing.T) {
+	saveGlobals(t)
+	years := []string{"-base-from", "01.01.2024", "-base-to", "31.12.2024", "-from", "01.01.2025"}
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "table",
+			args: years,
+			expected: `Category  Base  Current  Diff  Change   Status
+кафе      500   400      -100  -20.0%
+кино      300   0        -300  -100.0%  gone
+продукты  1000  1500     500   +50.0%
+спорт     0     2000     2000           new
+total     1800  3900     2100  +116.7%
+`,
+		},
+		{
+			name: "markdown sorted by difference",
+			args: append([]string{"-by", "person,category", "-sort", "diff", "-output", "md"}, years...),
+			expected: `| Person | Category | Base | Current | Diff | Change | Status |
+| --- | --- | ---: | ---: | ---: | --- | --- |
+| маша | спорт | 0 | 2000 | 2000 |  | new |
+| общие | продукты | 1000 | 1500 | 500 | +50.0% |  |
+| маша | кино | 300 | 0 | -300 | -100.0% | gone |
+| общие | кафе | 500 | 400 | -100 | -20.0% |  |
+| total |  | 1800 | 3900 | 2100 | +116.7% |  |
+`,
+		},
+		{
+			name: "csv",
+			args: append([]string{"-by", "year", "-output", "csv"}, years...),
+			expected: `Year,Base,Current,Diff,Change,Status
+2024,1800,0,-1800,-100.0%,gone
+2025,0,3900,3900,,new
+total,1800,3900,2100,+116.7%,
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, compareCommand(&buf, compareTestPurchases(t), tt.args))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, compareCommand(&buf, compareTestPurchases(t), []string{"-base-from", "01.01.2024", "-base-to", "31.12.2024", "-by", "year"}))
+	assert.Equal(t, `Year   Base  Current  Diff   Change   Status
+2024   1800  0        -1800  -100.0%  gone
+2025   0     3900     3900            new
+total  1800  3900     2100   +116.7%
+`, buf.String(), "current period starts the day after the base one")
+
+	buf.Reset()
+	assert.NoError(t, compareCommand(&buf, compareTestPurchases(t), []string{"-base-from", "01.01.2024", "-from", "01.01.2025", "-by", "year"}))
+	assert.Equal(t, `Year   Base  Current  Diff   Change   Status
+2024   1800  0        -1800  -100.0%  gone
+2025   0     3900     3900            new
+total  1800  3900     2100   +116.7%
+`, buf.String(), "base period ends the day before the current one")
+
+	for _, args := range [][]string{
+		nil,
+		{"-base-from", "01.01.2024"},
+		{"-base-to", "2024-12-31"},
+		{"-base-from", "01.01.2024", "-base-to", "31.12.2024", "-from", "01.06.2024"},
+		{"-base-to", "31.12.2024", "-by", ""},
+		{"-base-to", "31.12.2024", "-sort", "name"},
+		{"-base-to", "31.12.2024", "-output", "xml"},
+		{"-base-file", filepath.Join(t.TempDir(), "missing.csv")},
+	} {
+		assert.Error(t, compareCommand(&bytes.Buffer{}, compareTestPurchases(t), args), args)
+	}
+}
+
+func TestCompareBaseFile(t *testing.T) {
+	saveGlobals(t)
+	path := filepath.Join(t.TempDir(), "2023.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("Date,Items\n10.01.2023,\"продукты - хлеб (800), кафе (500)\"\n"), 0644))
+
+	var buf bytes.Buffer
+	assert.NoError(t, compareCommand(&buf, compareTestPurchases(t), []string{"-base-file", path, "-to", "31.12.2024", "-output", "csv"}))
+	expected := `Category,Base,Current,Diff,Change,Status
+кафе,500,500,0,+0.0%,
+кино,0,300,300,,new
+продукты,800,1000,200,+25.0%,
+total,1300,1800,500,+38.5%,
+`
+	assert.Equal(t, expected, buf.String())
+
+	assert.NoError(t, os.WriteFile(path, []byte("Date,Items\n10.01.2023,кафе (abc)\n"), 0644))
+	err := compareCommand(&buf, compareTestPurchases(t), []string{"-base-file", path})
+	assert.ErrorContains(t, err, "1 parse errors, the first is: unknown token abc, row: 2")
+}
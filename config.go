@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// External configuration, every setting is optional and built-in
+// defaults are used for missing ones
+type Config struct {
+	CategoryReplaces map[string]string `yaml:"category_replaces"`
+	// Parent category -> child categories, same as replacing every child with the parent
+	CategoryHierarchy map[string][]string `yaml:"category_hierarchy"`
+	PersonAliases     map[string]string   `yaml:"person_aliases"`
+	DefaultPerson     string              `yaml:"default_person"`
+	DateFormat        string              `yaml:"date_format"`
+	CurrencySymbols   map[string]string   `yaml:"currency_symbols"`
+	Accounts          map[string]string   `yaml:"accounts"`
+	Rules             []Rule              `yaml:"rules"`
+	// Entries of these categories are income, same as marked with "+"
+	IncomeCategories []string `yaml:"income_categories"`
+	// Spending limits checked by budget command
+	Budgets []Budget `yaml:"budgets"`
+	// Targets notified with -notify
+	Webhooks []Webhook `yaml:"webhooks"`
+	// Defaults for output flags, keyed by flag name like "format" or "row-group-size"
+	Output map[string]string `yaml:"output"`
+}
+
+// $XDG_CONFIG_HOME/finparser/config.yaml, $HOME/.config is used when XDG_CONFIG_HOME is not set
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "finparser", "config.yaml")
+}
+
+// Load config from path, or from default path if it's empty.
+// Missing default config is not an error, empty config is returned then.
+func loadConfig(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Apply config on top of built-in defaults. Flags explicitly set
+// on command line win over config values.
+func (cfg *Config) apply(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for k, v := range cfg.CategoryReplaces {
+		CATEGORY_REPLACES[strings.ToLower(k)] = strings.ToLower(v)
+	}
+	for parent, children := range cfg.CategoryHierarchy {
+		for _, child := range children {
+			CATEGORY_REPLACES[strings.ToLower(child)] = strings.ToLower(parent)
+		}
+	}
+	for k, v := range cfg.PersonAliases {
+		personAliases[strings.ToLower(k)] = strings.ToLower(v)
+	}
+	for _, category := range cfg.IncomeCategories {
+		incomeCategories[strings.ToLower(category)] = true
+	}
+	if cfg.DefaultPerson != "" {
+		defaultPerson = cfg.DefaultPerson
+	}
+	if len(cfg.CurrencySymbols) > 0 {
+		for k, v := range cfg.CurrencySymbols {
+			currencySymbols[k] = strings.ToUpper(v)
+		}
+		if err := compileCurrencyRegexps(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DateFormat != "" && !set["df"] {
+		df = cfg.DateFormat
+	}
+	compiled, err := compileRules(cfg.Rules)
+	if err != nil {
+		return err
+	}
+	rules = compiled
+	if budgets, err = checkBudgets(cfg.Budgets); err != nil {
+		return err
+	}
+	if webhooks, err = checkWebhooks(cfg.Webhooks); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(cfg.Output) {
+		if set[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown output option: %s", name)
+		}
+		if err := fs.Set(name, cfg.Output[name]); err != nil {
+			return fmt.Errorf("output option %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Person to account mapping from config, overridden by "person=account" command line mapping
+func (cfg *Config) accounts(mapping string) (map[string]string, error) {
+	accounts, err := parseMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range cfg.Accounts {
+		if _, ok := accounts[strings.ToLower(k)]; !ok {
+			accounts[strings.ToLower(k)] = v
+		}
+	}
+	return accounts, nil
+}
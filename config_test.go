@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Restore globals changed by Config.apply when test finishes
+func saveGlobals(t *testing.T) {
+	replaces := maps.Clone(CATEGORY_REPLACES)
+	aliases := maps.Clone(personAliases)
+	symbols := maps.Clone(currencySymbols)
+	income := maps.Clone(incomeCategories)
+	person, format, compiled, limits, hooks := defaultPerson, df, rules, budgets, webhooks
+	table, base := cpi, cpiBase
+	t.Cleanup(func() {
+		cpi, cpiBase = table, base
+		CATEGORY_REPLACES, personAliases, currencySymbols, incomeCategories = replaces, aliases, symbols, income
+		defaultPerson, df, rules, budgets, webhooks = person, format, compiled, limits, hooks
+		panicIfNotNil(compileCurrencyRegexps())
+	})
+}
+
+const testConfig = `
+category_replaces:
+  Такси: транспорт
+  мобильный: связь
+category_hierarchy:
+  еда: [кафе, Продукты]
+  кафе: [кофе]
+person_aliases:
+  Мария: маша
+default_person: Семья
+date_format: "2006-01-02"
+currency_symbols:
+  "₸": kzt
+accounts:
+  маша: Карта Маши
+  общие: Наличные
+income_categories: [Зарплата]
+budgets:
+  - category: Продукты
+    limit: 30000
+    rollover: true
+output:
+  format: xlsx
+  row-group-size: "1000"
+`
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testConfig), 0644))
+
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "транспорт", cfg.CategoryReplaces["Такси"])
+	assert.Equal(t, "Семья", cfg.DefaultPerson)
+	assert.Equal(t, "1000", cfg.Output["row-group-size"])
+
+	_, err = loadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err, "explicitly given config must exist")
+
+	assert.NoError(t, os.WriteFile(path, []byte("category_replaces: [1, 2"), 0644))
+	_, err = loadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestLoadDefaultConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	assert.Equal(t, filepath.Join(dir, "finparser", "config.yaml"), defaultConfigPath())
+
+	cfg, err := loadConfig("")
+	assert.NoError(t, err, "missing default config is fine")
+	assert.Equal(t, &Config{}, cfg)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "finparser"), 0755))
+	assert.NoError(t, os.WriteFile(defaultConfigPath(), []byte("default_person: Семья\n"), 0644))
+	cfg, err = loadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, "Семья", cfg.DefaultPerson)
+}
+
+func TestConfigApply(t *testing.T) {
+	saveGlobals(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testConfig), 0644))
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+
+	var format string
+	var rowGroupSize int64
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&df, "df", "02.01.2006", "")
+	fs.StringVar(&format, "format", "csv", "")
+	fs.Int64Var(&rowGroupSize, "row-group-size", 0, "")
+	assert.NoError(t, fs.Parse([]string{"-format", "parquet"}))
+
+	assert.NoError(t, cfg.apply(fs))
+	assert.Equal(t, "parquet", format, "command line wins over config")
+	assert.Equal(t, int64(1000), rowGroupSize)
+	assert.Equal(t, "2006-01-02", df)
+	assert.Equal(t, "транспорт", CATEGORY_REPLACES["такси"])
+	assert.Equal(t, "транспорт", CATEGORY_REPLACES["автобус"], "built-in replacements are kept")
+	assert.Equal(t, []string{"еда", "кафе", "кофе"}, categoryPath("кофе"))
+	assert.Equal(t, []string{"еда", "продукты"}, categoryPath("продукты"))
+
+	c, err := newCommodity("Мария/такси (500)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "маша", c.person)
+	assert.Equal(t, "транспорт", c.category)
+
+	person, _, _, err := parseDesc("мобильный")
+	assert.NoError(t, err)
+	assert.Equal(t, "семья", person)
+
+	c, err = newCommodity("Поездка (₸1000=200)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 200, c.price)
+	assert.Equal(t, "KZT", c.currency)
+
+	c, err = newCommodity("Маша/зарплата (100000)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, INCOME, c.entryType())
+
+	assert.Equal(t, []Budget{{Name: "продукты", Category: "продукты", Limit: 30000, Period: "month", Rollover: true}}, budgets)
+
+	accounts, err := cfg.accounts("общие=Кошелёк")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"маша": "Карта Маши", "общие": "Кошелёк"}, accounts)
+}
+
+func TestConfigApplyUnknownOption(t *testing.T) {
+	saveGlobals(t)
+	cfg := &Config{Output: map[string]string{"colour": "red"}}
+	assert.Error(t, cfg.apply(flag.NewFlagSet("test", flag.ContinueOnError)))
+}
+
+func TestConfigApplyInvalidBudget(t *testing.T) {
+	saveGlobals(t)
+	cfg := &Config{Budgets: []Budget{{Category: "кафе", Period: "week", Limit: 100}}}
+	assert.EqualError(t, cfg.apply(flag.NewFlagSet("test", flag.ContinueOnError)), "budget 1: unknown period: week")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rosstat consumer price index, December to December of the previous year
+//
+//go:embed cpi.csv
+var defaultCPI string
+
+const cpiMonthFormat = "2006-01"
+
+// Price level per month, built from a CPI series
+type cpiTable struct {
+	levels      map[time.Time]float64
+	first, last time.Time
+}
+
+var (
+	cpi     *cpiTable
+	cpiBase time.Time // month of constant roubles, the last month of CPI table if zero
+)
+
+// Parse "Period,CPI" CSV with header. Period is either a year with CPI in percents
+// to December of the previous year, or a YYYY-MM month with CPI in percents to
+// the previous month. Annual CPI is spread evenly over months of the year,
+// a year has either annual or monthly CPI.
+func parseCPI(r io.Reader) (*cpiTable, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	annual := map[int]float64{}
+	monthly := map[time.Time]float64{}
+	var first, last time.Time
+	extend := func(from, to time.Time) {
+		if first.IsZero() || from.Before(first) {
+			first = from
+		}
+		if last.IsZero() || to.After(last) {
+			last = to
+		}
+	}
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("CPI row %d: expected period and CPI", i+1)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("CPI row %d: invalid CPI: %s", i+1, record[1])
+		}
+		period := strings.TrimSpace(record[0])
+		if month, err := time.Parse(cpiMonthFormat, period); err == nil {
+			monthly[month] = value / 100
+			extend(month, month)
+		} else if year, err := strconv.Atoi(period); err == nil {
+			annual[year] = value / 100
+			extend(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year, 12, 1, 0, 0, 0, 0, time.UTC))
+		} else {
+			return nil, fmt.Errorf("CPI row %d: invalid period: %s", i+1, period)
+		}
+	}
+	if first.IsZero() {
+		return nil, fmt.Errorf("empty CPI table")
+	}
+	// Annual CPI would be spread over months without monthly CPI as if it
+	// was for the whole year, so a year can't have both
+	for month := range monthly {
+		if _, ok := annual[month.Year()]; ok {
+			return nil, fmt.Errorf("both annual and monthly CPI for %d", month.Year())
+		}
+	}
+
+	t := &cpiTable{levels: map[time.Time]float64{}, first: first, last: last}
+	level := 1.0
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		if v, ok := monthly[month]; ok {
+			level *= v
+		} else if v, ok := annual[month.Year()]; ok {
+			level *= math.Pow(v, 1.0/12)
+		} else {
+			return nil, fmt.Errorf("no CPI for %s", month.Format(cpiMonthFormat))
+		}
+		t.levels[month] = level
+	}
+	return t, nil
+}
+
+// Table has the price level of the month of the date
+func (t *cpiTable) covers(d time.Time) bool {
+	month := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return !month.Before(t.first) && !month.After(t.last)
+}
+
+// Price level of the month of the date, the table must cover it
+func (t *cpiTable) level(d time.Time) float64 {
+	return t.levels[time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)]
+}
+
+// Restate roubles paid on the date in constant roubles of the base month,
+// false if the table doesn't cover the date or the base month
+func (t *cpiTable) restate(price int, date, base time.Time) (int, bool) {
+	if base.IsZero() {
+		base = t.last
+	}
+	if !t.covers(date) || !t.covers(base) {
+		return 0, false
+	}
+	return int(math.Round(float64(price) * t.level(base) / t.level(date))), true
+}
+
+// Price in constant roubles of cpiBase month, false if CPI table doesn't cover the date
+func (p Purchase) realPrice() (int, bool) {
+	return cpi.restate(p.commodity.price, p.date, cpiBase)
+}
+
+// Purchases with prices restated in constant roubles. Months out of CPI
+// table are an error rather than a guess, a newer table is needed for them.
+func (pp Purchases) real() (Purchases, error) {
+	var result Purchases
+	for _, p := range pp {
+		price, ok := p.realPrice()
+		if !ok {
+			return nil, fmt.Errorf("no CPI for %s, CPI table covers %s to %s, pass a newer one with -cpi",
+				p.date.Format(cpiMonthFormat), cpi.first.Format(cpiMonthFormat), cpi.last.Format(cpiMonthFormat))
+		}
+		c := *p.commodity
+		c.price = price
+		restated := *p
+		restated.commodity = &c
+		result = append(result, &restated)
+	}
+	return result, nil
+}
+
+// Number of purchases out of CPI table, they have no real price
+func (pp Purchases) withoutCPI() int {
+	var n int
+	for _, p := range pp {
+		if !cpi.covers(p.date) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPI(t *testing.T) {
+	table, err := parseCPI(strings.NewReader(`Period,CPI
+2023,112
+2024-01,101
+2024-02,99
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), table.first)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), table.last)
+
+	dec := time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	assert.InDelta(t, 1.12, table.level(dec), 1e-9, "annual CPI is spread over the year")
+	assert.InDelta(t, math.Pow(1.12, 0.5), table.level(jun), 1e-9)
+	assert.InDelta(t, 1.12*1.01*0.99, table.level(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)), 1e-9)
+	assert.True(t, table.covers(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, table.covers(time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, table.covers(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	restate := func(price int, date, base time.Time) int {
+		v, ok := table.restate(price, date, base)
+		assert.True(t, ok)
+		return v
+	}
+	assert.Equal(t, 1000, restate(1000, dec, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 1010, restate(1000, dec, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 1000, restate(1010, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), dec))
+	assert.Equal(t, 1000, restate(1000, time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), time.Time{}), "last month is the default base")
+
+	_, ok := table.restate(1000, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	assert.False(t, ok, "later months aren't guessed")
+	_, ok = table.restate(1000, dec, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok, "base month must be in the table")
+}
+
+// CPI table with flat 2023 prices and 10% inflation in January 2024
+func testCPI(t *testing.T) *cpiTable {
+	table, err := parseCPI(strings.NewReader("Period,CPI\n2023,100\n2024-01,110\n"))
+	assert.NoError(t, err)
+	return table
+}
+
+func TestParseCPIErrors(t *testing.T) {
+	for _, input := range []string{
+		"Period,CPI\n",
+		"Period,CPI\n2023\n",
+		"Period,CPI\n2023,abc\n",
+		"Period,CPI\n2023,-1\n",
+		"Period,CPI\nянварь,101\n",
+		"Period,CPI\n2022,110\n2024,110\n",
+		"Period,CPI\n2024,110\n2024-01,101\n",
+	} {
+		_, err := parseCPI(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestDefaultCPI(t *testing.T) {
+	table, err := parseCPI(strings.NewReader(defaultCPI))
+	assert.NoError(t, err)
+	dec2023 := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	dec2024 := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), table.last)
+	price, ok := table.restate(10000, dec2023, dec2024)
+	assert.True(t, ok)
+	assert.Equal(t, 10952, price)
+}
+
+func TestRealPurchases(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	var err error
+	cpi, err = parseCPI(strings.NewReader("Period,CPI\n2024-01,100\n2024-02,110\n"))
+	assert.NoError(t, err)
+
+	records := [][]string{
+		{"Date", "Items"},
+		{"10.01.2024", "+зарплата (1100), кафе (1000)"},
+		{"10.02.2024", "+зарплата (1100), кафе (1100)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	assert.Equal(t, "1100", purchases[1].toArray()[len(purchaseColumns)-1])
+
+	restated, err := purchases.real()
+	assert.NoError(t, err)
+	assert.Equal(t, 1100, restated[1].commodity.price)
+	assert.Equal(t, 1000, purchases[1].commodity.price, "original purchases are kept")
+
+	cpiBase = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	assert.NoError(t, cashflowCommand(&buf, purchases, []string{"-real"}))
+	expected := `Month    Person  Income  Expenses  Savings  Rate
+2024-01  общие   1100    1000      100      9.1%
+2024-02  общие   1000    1000      0        0.0%
+`
+	assert.Equal(t, expected, buf.String())
+
+	late := append(purchases, &Purchase{date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), commodity: &Commodity{price: 100}})
+	assert.Equal(t, 1, late.withoutCPI())
+	_, err = late.real()
+	assert.EqualError(t, err, "no CPI for 2024-03, CPI table covers 2024-01 to 2024-02, pass a newer one with -cpi")
+	assert.Error(t, cashflowCommand(&buf, late, []string{"-real"}))
+}
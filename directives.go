@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Date cells of directive rows
+const (
+	DIRECTIVE_SET   = "#set"
+	DIRECTIVE_RESET = "#reset"
+)
+
+// Defaults for items of subsequent rows set by "#set" directive rows
+// until reset by "#reset" ones
+type rowContext struct {
+	currency string // currency code of prices without currency symbol
+	person   string // person of items without person
+	account  string // account of items without account marker and account column
+	tags     []string
+}
+
+// Apply directive row to the context, returns false if the row isn't
+// a directive. "#set" row has "key=value" pairs separated with spaces
+// in the second cell, an empty value resets the key. "#reset" row resets
+// listed keys, or all of them if there are none.
+func (ctx *rowContext) apply(record []string) (bool, error) {
+	directive := strings.ToLower(strings.TrimSpace(record[0]))
+	if directive != DIRECTIVE_SET && directive != DIRECTIVE_RESET {
+		return false, nil
+	}
+	var args []string
+	if len(record) > 1 {
+		args = strings.Fields(record[1])
+	}
+
+	if directive == DIRECTIVE_RESET {
+		if len(args) == 0 {
+			*ctx = rowContext{}
+			return true, nil
+		}
+		for _, key := range args {
+			if err := ctx.set(key, ""); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	if len(args) == 0 {
+		return true, fmt.Errorf("nothing to set")
+	}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return true, fmt.Errorf("invalid directive argument: %s", arg)
+		}
+		if err := ctx.set(key, value); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Currency is either a symbol from currencySymbols or a currency code
+func (ctx *rowContext) set(key, value string) error {
+	switch strings.ToLower(key) {
+	case "currency":
+		if value == "" {
+			ctx.currency = ""
+		} else if code, ok := currencySymbols[value]; ok {
+			ctx.currency = code
+		} else if code := strings.ToUpper(value); reCurrencyCode.MatchString(code) {
+			ctx.currency = code
+		} else {
+			return fmt.Errorf("unknown currency: %s", value)
+		}
+	case "person":
+		ctx.person = ""
+		if value != "" {
+			ctx.person = resolvePerson(strings.ToLower(value))
+		}
+	case "account":
+		ctx.account = strings.ToLower(value)
+	case "tag", "tags":
+		ctx.tags = parseTags(value)
+	default:
+		return fmt.Errorf("unknown directive key: %s", key)
+	}
+	return nil
+}
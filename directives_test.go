@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowContextApply(t *testing.T) {
+	saveGlobals(t)
+	personAliases["мария"] = "маша"
+
+	tests := []struct {
+		name      string
+		record    []string
+		expected  rowContext
+		directive bool
+		wantErr   bool
+	}{
+		{
+			name:   "not a directive",
+			record: []string{"01.03.2024", "хлеб (50)"},
+		},
+		{
+			name:      "set all",
+			record:    []string{"#set", "currency=€ person=Мария account=Карта tag=армения-2024,Отпуск"},
+			expected:  rowContext{currency: "EUR", person: "маша", account: "карта", tags: []string{"армения-2024", "отпуск"}},
+			directive: true,
+		},
+		{
+			name:      "currency code",
+			record:    []string{" #SET ", "currency=amd"},
+			expected:  rowContext{currency: "AMD"},
+			directive: true,
+		},
+		{
+			name:      "reset all",
+			record:    []string{"#reset", ""},
+			directive: true,
+		},
+		{
+			name:      "unknown key",
+			record:    []string{"#set", "colour=red"},
+			directive: true,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown currency",
+			record:    []string{"#set", "currency=euro"},
+			directive: true,
+			wantErr:   true,
+		},
+		{
+			name:      "no value",
+			record:    []string{"#set", "currency"},
+			directive: true,
+			wantErr:   true,
+		},
+		{
+			name:      "nothing to set",
+			record:    []string{"#set"},
+			directive: true,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx rowContext
+			directive, err := ctx.apply(tt.record)
+			assert.Equal(t, tt.directive, directive)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ctx)
+		})
+	}
+}
+
+func TestRowContextReset(t *testing.T) {
+	ctx := rowContext{currency: "EUR", person: "маша", account: "карта", tags: []string{"отпуск"}}
+	_, err := ctx.apply([]string{"#reset", "currency tag"})
+	assert.NoError(t, err)
+	assert.Equal(t, rowContext{person: "маша", account: "карта"}, ctx)
+
+	_, err = ctx.apply([]string{"#set", "person="})
+	assert.NoError(t, err)
+	assert.Equal(t, rowContext{account: "карта"}, ctx)
+
+	_, err = ctx.apply([]string{"#reset"})
+	assert.NoError(t, err)
+	assert.Equal(t, rowContext{}, ctx)
+}
+
+func TestGetPurchasesDirectives(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items", "Account"},
+		{"#set", "person=маша account=карта tag=армения-2024"},
+		{"01.03.2024", "кафе (2500) #ужин, Петя/такси (700), хлеб (50) [наличные]", ""},
+		{"02.03.2024", "музей (1000)", "наличные"},
+		{"#set", "colour=red"},
+		{"#reset", "person tag"},
+		{"03.03.2024", "хлеб (50)", ""},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Equal(t, []*ParseError{{"unknown directive key: colour", 5}}, errors)
+
+	var actual [][]string
+	for _, p := range purchases {
+		c := p.commodity
+		actual = append(actual, []string{c.person, c.name, c.account, strings.Join(c.tags, TAGS_SEPARATOR)})
+	}
+	assert.Equal(t, [][]string{
+		{"маша", "кафе", "карта", "ужин;армения-2024"},
+		{"петя", "такси", "карта", "армения-2024"},
+		{"маша", "хлеб", "наличные", "армения-2024"},
+		{"маша", "музей", "наличные", "армения-2024"},
+		{"общие", "хлеб", "карта", ""},
+	}, actual)
+}
+
+func TestParseCommodityDefaultCurrency(t *testing.T) {
+	saveGlobals(t)
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	ctx := rowContext{currency: "EUR"}
+
+	c, err := parseCommodity("кафе (12)", date, ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", c.currency)
+	assert.Equal(t, 12.0, c.amount)
+	assert.Equal(t, int(math.Round(12*getCurrencyRate("EUR", date))), c.price)
+
+	c, err = parseCommodity("кафе ($10=900)", date, ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", c.currency, "explicit currency wins")
+	assert.Equal(t, 900, c.price)
+
+	for _, s := range []string{"кафе (₽500)", "кафе (₽2*250)"} {
+		c, err = parseCommodity(s, date, ctx)
+		assert.NoError(t, err, s)
+		assert.Equal(t, DEFAULT_CURRENCY, c.currency, "explicit roubles win")
+		assert.Equal(t, 500, c.price, s)
+		assert.Equal(t, 500.0, c.amount, s)
+	}
+
+	c, err = parseCommodity("кафе (12)", date, rowContext{currency: DEFAULT_CURRENCY})
+	assert.NoError(t, err)
+	assert.Equal(t, DEFAULT_CURRENCY, c.currency)
+	assert.Equal(t, 12, c.price)
+}
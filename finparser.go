@@ -5,10 +5,12 @@ import (
 	"encoding/csv"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,6 +20,10 @@ import (
 )
 
 const DEFAULT_PERSON = "Общие"
+const DEFAULT_CURRENCY = "RUB"
+
+// Marks prices in roubles explicitly, like "(₽500)"
+const ROUBLE_SYMBOL = "₽"
 
 var CATEGORY_REPLACES = map[string]string{
 	"автобус":    "транспорт",
@@ -39,27 +45,78 @@ func (e ParseError) Error() string {
 }
 
 type Commodity struct {
-	person   string
-	category string
-	name     string
-	price    int
+	person       string
+	category     string
+	subcategory  string // rest of category path below the root, items are joined with ":"
+	origCategory string // category path as written, before CATEGORY_REPLACES applied
+	name         string
+	price        int           // in roubles
+	currency     string        // currency code of the original price
+	amount       float64       // price in original currency
+	rule         string        // name of the auto-categorisation rule applied
+	participants []participant // persons sharing the purchase, see split
+	payer        string        // person who paid for the purchase, if marked with "@person"
+	account      string        // account paid from, if marked with "[account]" or set for the row
+	toAccount    string        // account money is moved to, if it's a "[from>to]" transfer
+	income       bool          // marked with "+" or of one of incomeCategories
+	quantity     float64       // in base unit, zero if unknown
+	unit         string        // base unit of quantity, see units
+	tags         []string      // "#tag" markers
+	note         string        // trailing "// note"
 }
 
 type Purchase struct {
 	date      time.Time
 	commodity *Commodity
+	row       int    // input row number, starting from 1 for the header
+	item      int    // position of the commodity in the row, starting from 1
+	source    string // commodity text as written in the row
 }
 
 func (p Purchase) toArray() []string {
+	// Real price is empty for months out of CPI table
+	var realPrice string
+	if price, ok := p.realPrice(); ok {
+		realPrice = strconv.Itoa(price)
+	}
 	return []string{
 		p.date.Format(df),
 		p.commodity.person,
 		p.commodity.category,
 		p.commodity.name,
 		strconv.Itoa(p.commodity.price),
+		p.commodity.rule,
+		p.commodity.subcategory,
+		p.commodity.account,
+		string(p.commodity.entryType()),
+		strings.Join(p.commodity.tags, TAGS_SEPARATOR),
+		p.commodity.note,
+		formatQuantity(p.commodity.quantity),
+		p.commodity.unit,
+		formatQuantity(p.commodity.unitPrice()),
+		realPrice,
 	}
 }
 
+// Columns of the purchases CSV, in the same order as Purchase.toArray
+var purchaseColumns = []column{
+	{"Date", kindDate},
+	{"Person", kindDict},
+	{"Category", kindDict},
+	{"Name", kindString},
+	{"Price", kindDecimal},
+	{"Rule", kindDict},
+	{"Subcategory", kindDict},
+	{"Account", kindDict},
+	{"Type", kindDict},
+	{"Tags", kindString},
+	{"Note", kindString},
+	{"Quantity", kindDecimal},
+	{"Unit", kindDict},
+	{"UnitPrice", kindDecimal},
+	{"RealPrice", kindDecimal},
+}
+
 type Purchases []*Purchase
 
 func (pp Purchases) toCsv() [][]string {
@@ -70,10 +127,23 @@ func (pp Purchases) toCsv() [][]string {
 	return c
 }
 
+func (pp Purchases) toTable() *table {
+	return &table{name: "purchases", columns: purchaseColumns, rows: pp.toCsv()}
+}
+
 var (
 	l               *log.Logger
 	df              string
+	defaultPerson   = DEFAULT_PERSON
+	personAliases   = map[string]string{}
 	re1, re2, re3   *regexp.Regexp
+	rePayer         *regexp.Regexp
+	reAccount       *regexp.Regexp
+	reTag, reNote   *regexp.Regexp
+	reCurrencyCode  *regexp.Regexp
+	reQuantity      *regexp.Regexp
+	rePerUnit       *regexp.Regexp
+	reTimes         *regexp.Regexp
 	currencySymbols = map[string]string{"$": "USD", "€": "EUR", "Br": "BYN", "֏": "AMD"}
 )
 
@@ -81,13 +151,48 @@ func init() {
 	var err error
 	re1, err = regexp.Compile("^\\d+$")
 	panicIfNotNil(err)
-	re2, err = regexp.Compile("^([$€]|Br|֏)\\d+(\\.\\d+)*=(\\d+)$")
+	panicIfNotNil(compileCurrencyRegexps())
+	cpi, err = parseCPI(strings.NewReader(defaultCPI))
+	panicIfNotNil(err)
+	rePayer, err = regexp.Compile("(^|\\s)@(\\S+)")
+	panicIfNotNil(err)
+	reAccount, err = regexp.Compile("\\[([^\\[\\]]*)\\]")
+	panicIfNotNil(err)
+	reTag, err = regexp.Compile("(^|\\s)#(\\pL[^\\s(),]*)")
 	panicIfNotNil(err)
-	re3, err = regexp.Compile("^([$€]|Br|֏)(\\d+)$")
+	reNote, err = regexp.Compile("(^|\\s)//")
+	panicIfNotNil(err)
+	reCurrencyCode, err = regexp.Compile("^[A-Z]{3}$")
+	panicIfNotNil(err)
+	reQuantity, err = regexp.Compile("(?i)\\s(\\d+(?:\\.\\d+)?)\\s?(" + unitsAlternation() + ")$")
+	panicIfNotNil(err)
+	rePerUnit, err = regexp.Compile("^([^\\d\\s/]*\\s*\\d+(?:\\.\\d+)?)\\s*/\\s*([^\\d\\s/]+)$")
+	panicIfNotNil(err)
+	reTimes, err = regexp.Compile("(?i)^(?:(\\d+(?:\\.\\d+)?)\\s?(" + unitsAlternation() + ")\\s*)?[×*]\\s*([^\\d\\s×*]*\\d+(?:\\.\\d+)?)$")
 	panicIfNotNil(err)
 	cbr.UpdateCurrencyRates()
 }
 
+// Build price regexps from currencySymbols, longest symbols first
+// so that "Br" isn't shadowed by a one-letter symbol
+func compileCurrencyRegexps() error {
+	symbols := sortedKeys(currencySymbols)
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return len(symbols[i]) > len(symbols[j])
+	})
+	for i, symbol := range symbols {
+		symbols[i] = regexp.QuoteMeta(symbol)
+	}
+	alternation := strings.Join(symbols, "|")
+
+	var err error
+	if re2, err = regexp.Compile("^(" + alternation + ")\\d+(\\.\\d+)*=(\\d+)$"); err != nil {
+		return err
+	}
+	re3, err = regexp.Compile("^(" + alternation + ")(\\d+)$")
+	return err
+}
+
 func panicIfNotNil(err error) {
 	if err != nil {
 		panic(err)
@@ -103,13 +208,39 @@ func isEmpty(records []string) bool {
 	return true
 }
 
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Input string formats:
 // - "person/category - name" - it's all clear;
 // - "person/category" - name=category;
 // - "category - name" - person is empty;
 // - "name" - person is empty, category=name.
-// Returns person, category, name, error.
+// Category may be a path like "транспорт:метро" or, after person,
+// "person/еда/кафе". Name defaults to the last item of the first "/" part,
+// so "person/еда/кафе" is named "еда" as before "/" paths.
+// Returns person, root category, name, error.
 func parseDesc(s string) (string, string, string, error) {
+	person, category, name, err := splitDesc(s)
+	if err != nil {
+		return "", "", "", err
+	}
+	return person, replaceCategory(category), name, nil
+}
+
+// Same as parseDesc but keeps category path as is, items are joined with ":"
+func splitDesc(s string) (string, string, string, error) {
+	return splitDescFor(s, defaultPerson)
+}
+
+// Same as splitDesc with another default person
+func splitDescFor(s, defaultPerson string) (string, string, string, error) {
 	var person, category, name string
 	items := strings.Split(s, " - ")
 	if len(items) < 1 && len(items) > 2 {
@@ -124,33 +255,74 @@ func parseDesc(s string) (string, string, string, error) {
 		return "", "", "", fmt.Errorf("invalid person/category format: %s", items[0])
 	}
 
+	var path []string
 	if len(subItems) >= 2 {
 		person = strings.TrimSpace(subItems[0])
-		category = strings.TrimSpace(subItems[1])
+		path = subItems[1:]
 	} else {
-		person = DEFAULT_PERSON
-		category = strings.TrimSpace(subItems[0])
+		person = defaultPerson
+		path = subItems
+	}
+
+	// Get ["еда", "кафе"] from "еда:кафе" or "еда/кафе"
+	var segments []string
+	var first string
+	for i, item := range path {
+		for _, segment := range strings.Split(item, ":") {
+			if segment = strings.TrimSpace(segment); segment != "" {
+				segments = append(segments, segment)
+				if i == 0 {
+					first = segment
+				}
+			}
+		}
 	}
+	if len(segments) == 0 {
+		return "", "", "", fmt.Errorf("invalid category format: %s", items[0])
+	}
+	category = strings.Join(segments, ":")
 
 	if len(items) == 2 {
 		name = strings.TrimSpace(items[1])
+	} else if first != "" {
+		name = first
 	} else {
-		if len(subItems) > 1 {
-			name = strings.TrimSpace(subItems[1])
-		} else {
-			name = strings.TrimSpace(subItems[0])
-		}
+		name = segments[len(segments)-1]
 	}
 
 	person = strings.ToLower(person)
 	category = strings.ToLower(category)
 	name = strings.ToLower(name)
 
-	if v, ok := CATEGORY_REPLACES[category]; ok {
-		category = v
+	return person, category, name, nil
+}
+
+// Return canonical person name for alias
+func resolvePerson(person string) string {
+	if v, ok := personAliases[person]; ok {
+		return v
 	}
+	return person
+}
 
-	return person, category, name, nil
+// Return full category path from the root, parents of the first item
+// come from CATEGORY_REPLACES, e.g. [транспорт метро] for "метро"
+func categoryPath(category string) []string {
+	path := strings.Split(category, ":")
+	seen := map[string]bool{}
+	for {
+		parent, ok := CATEGORY_REPLACES[path[0]]
+		if !ok || seen[parent] {
+			return path
+		}
+		seen[path[0]] = true
+		path = append([]string{parent}, path...)
+	}
+}
+
+// Return root category for category path
+func replaceCategory(category string) string {
+	return categoryPath(category)[0]
 }
 
 // Parse strings like "123+456+789", "2*400", "$5=338" or "€17" and return sum in roubles
@@ -185,6 +357,22 @@ func parsePriceExpr(s string, date time.Time) (int, error) {
 	return sum, nil
 }
 
+// Return currency code and amount in that currency for price expression,
+// expressions without currency symbol are in roubles already
+func parseCurrency(s string, price int) (string, float64) {
+	if tokens := re2.FindStringSubmatch(s); tokens != nil {
+		strAmount := strings.TrimPrefix(strings.Split(s, "=")[0], tokens[1])
+		if amount, err := strconv.ParseFloat(strAmount, 64); err == nil {
+			return currencySymbols[tokens[1]], amount
+		}
+	} else if tokens := re3.FindStringSubmatch(s); tokens != nil {
+		if amount, err := strconv.ParseFloat(tokens[2], 64); err == nil {
+			return currencySymbols[tokens[1]], amount
+		}
+	}
+	return DEFAULT_CURRENCY, float64(price)
+}
+
 func getCurrencyRate(code string, d time.Time) float64 {
 	if d.IsZero() {
 		return cbr.GetCurrencyRates()[code].Value
@@ -197,65 +385,280 @@ func getCurrencyRate(code string, d time.Time) float64 {
 	}
 }
 
+// Cut "@person" payer marker out of item text
+func cutPayer(s string) (string, string) {
+	var payer string
+	s = rePayer.ReplaceAllStringFunc(s, func(m string) string {
+		payer = rePayer.FindStringSubmatch(m)[2]
+		return ""
+	})
+	if payer != "" {
+		payer = resolvePerson(strings.ToLower(payer))
+	}
+	return s, payer
+}
+
 func newCommodity(s string, date time.Time) (*Commodity, error) {
+	return parseCommodity(s, date, rowContext{})
+}
+
+// Parse commodity with defaults set by directive rows
+func parseCommodity(s string, date time.Time, ctx rowContext) (*Commodity, error) {
+	s, note, err := cutNote(s)
+	if err != nil {
+		return nil, err
+	}
+	s, tags := cutTags(s)
+	s, payer := cutPayer(s)
+	s, account, toAccount, err := cutAccount(s)
+	if err != nil {
+		return nil, err
+	}
 	tokens := strings.Split(s, "(")
 	if len(tokens) < 2 {
-		return nil, fmt.Errorf("can't parse: %s", s)
+		// "яблоки - 2кг × 150" has unit price without parentheses
+		desc, unitPrice, ok := strings.Cut(s, "×")
+		if !ok {
+			return nil, fmt.Errorf("can't parse: %s", s)
+		}
+		tokens = []string{desc, "×" + unitPrice}
+	}
+	desc, m := cutQuantity(strings.TrimSpace(tokens[0]))
+	income := strings.HasPrefix(desc, "+")
+	desc = strings.TrimSpace(strings.TrimPrefix(desc, "+"))
+	if desc == "" && toAccount != "" {
+		desc = TRANSFER_CATEGORY
 	}
-	desc := strings.TrimSpace(tokens[0])
 	strPrice := strings.TrimRight(strings.TrimSpace(tokens[1]), ")")
-	person, category, name, err := parseDesc(desc)
+	fallback := defaultPerson
+	if ctx.person != "" {
+		fallback = ctx.person
+	}
+	person, category, name, err := splitDescFor(desc, fallback)
 	if err != nil {
 		return nil, err
 	}
-	price, err := parsePriceExpr(strPrice, date)
+	// Explicit roubles aren't converted from currency of directive rows
+	strPrice, roubles := strings.CutPrefix(strPrice, ROUBLE_SYMBOL)
+	var price int
+	var currency string
+	var amount float64
+	total, code, priced, measured, err := parseUnitPrice(strPrice, m)
 	if err != nil {
 		return nil, err
 	}
-	return &Commodity{person, category, name, price}, nil
+	if measured {
+		m = priced
+		currency, amount = code, total
+		price = int(math.Round(amount))
+		if currency != DEFAULT_CURRENCY {
+			price = int(math.Round(amount * getCurrencyRate(currency, date)))
+		}
+	} else {
+		if price, err = parsePriceExpr(strPrice, date); err != nil {
+			return nil, err
+		}
+		currency, amount = parseCurrency(strPrice, price)
+	}
+	if !roubles && currency == DEFAULT_CURRENCY && ctx.currency != "" && ctx.currency != DEFAULT_CURRENCY {
+		currency = ctx.currency
+		price = int(math.Round(amount * getCurrencyRate(currency, date)))
+	}
+	participants, err := parseParticipants(person)
+	if err != nil {
+		return nil, err
+	}
+	if participants != nil {
+		var persons []string
+		for _, p := range participants {
+			persons = append(persons, p.person)
+		}
+		person = strings.Join(persons, "+")
+	} else {
+		person = resolvePerson(person)
+	}
+	c := &Commodity{
+		person:       person,
+		participants: participants,
+		payer:        payer,
+		origCategory: category,
+		name:         name,
+		price:        price,
+		currency:     currency,
+		amount:       amount,
+		account:      account,
+		toAccount:    toAccount,
+		note:         note,
+	}
+	if m != nil {
+		c.quantity, c.unit = m.normalize()
+	}
+	c.addTags(tags...)
+	c.addTags(ctx.tags...)
+	c.setCategory(category)
+	if !c.isTransfer() {
+		applyRules(c, date)
+		c.income = income || incomeCategories[c.category] || incomeCategories[c.origCategory]
+	}
+	return c, nil
+}
+
+// Set root category and subcategory from category path
+func (c *Commodity) setCategory(category string) {
+	path := categoryPath(category)
+	c.category = path[0]
+	c.subcategory = strings.Join(path[1:], ":")
 }
 
 func getPurchases(records [][]string) (Purchases, []*ParseError) {
 	var purchases []*Purchase
 	var errors []*ParseError
+	var ctx rowContext
+	// Account column is used only when the header has one
+	accountColumn := -1
 	for row, record := range records {
 		if row == 0 {
+			for i, name := range record {
+				if i > 1 && strings.EqualFold(strings.TrimSpace(name), "account") {
+					accountColumn = i
+				}
+			}
 			continue
 		}
 		if isEmpty(record) {
 			continue
 		}
 
+		// Directive rows set defaults for the following rows
+		if ok, err := ctx.apply(record); ok {
+			if err != nil {
+				errors = append(errors, &ParseError{err.Error(), row + 1})
+			}
+			continue
+		}
+
 		// First field of record is a date, but if it's not a date - it's ok
 		date, err := time.Parse(df, record[0])
 		if err != nil {
 			continue
 		}
 
+		// Optional Account column is an account of commodities without account marker
+		account := ctx.account
+		if accountColumn > 0 && len(record) > accountColumn && strings.TrimSpace(record[accountColumn]) != "" {
+			account = strings.ToLower(strings.TrimSpace(record[accountColumn]))
+		}
+
 		// Second field of record is commodity list in text format
 		commodities := strings.Split(record[1], ",")
-		for _, s := range commodities {
-			commodity, err := newCommodity(s, date)
+		for item, s := range commodities {
+			commodity, err := parseCommodity(s, date, ctx)
 			if err != nil {
 				errors = append(errors, &ParseError{err.Error(), row + 1})
 				continue
 			}
-			purchase := &Purchase{
-				date:      date,
-				commodity: commodity,
+			if commodity.account == "" {
+				commodity.account = account
+			}
+			for _, c := range commodity.split() {
+				purchase := &Purchase{
+					date:      date,
+					commodity: c,
+					row:       row + 1,
+					item:      item + 1,
+					source:    strings.TrimSpace(s),
+				}
+				purchases = append(purchases, purchase)
 			}
-			purchases = append(purchases, purchase)
 		}
 	}
 	return purchases, errors
 }
 
+// Write to file or to stdout if path is empty
+func writeOutput(path string, write func(io.Writer) error) error {
+	f := os.Stdout
+	if path != "" {
+		var err error
+		if f, err = os.Create(path); err != nil {
+			return err
+		}
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func main() {
+	var configPath, format, starFormat, out, qvs, qvsFrom, influxPeriod, accountsMapping, cpiPath, base, notifiedPath string
+	var rowGroupSize int64
+	var notifyWebhooks, dryRun, warnAnomalies, allEntries bool
+	flag.StringVar(&configPath, "config", "", "Config file, $XDG_CONFIG_HOME/finparser/config.yaml by default")
 	flag.StringVar(&df, "df", "02.01.2006", "Golang date format")
+	flag.StringVar(&format, "format", "csv", "Output format: csv, parquet, xlsx, star, influx, prom, ynab, firefly or actual")
+	flag.StringVar(&starFormat, "star-format", "csv", "File format of star schema tables: csv or parquet")
+	flag.StringVar(&out, "out", "", "Output file, or output directory for star format")
+	flag.Int64Var(&rowGroupSize, "row-group-size", 0, "Maximum number of rows per Parquet row group, 0 for no limit")
+	flag.StringVar(&influxPeriod, "influx-period", "", "Aggregate InfluxDB points by day or month, every purchase is a point by default")
+	flag.StringVar(&accountsMapping, "accounts", "", "Person to account mapping for budgeting apps formats, like \"маша=Карта Маши,общие=Наличные\"")
+	flag.StringVar(&qvs, "qvs", "", "Write Qlik load script for the produced CSV to this file")
+	flag.StringVar(&qvsFrom, "qvs-from", "purchases.csv", "Data file path referenced by the Qlik load script")
+	flag.StringVar(&cpiPath, "cpi", "", "CPI table CSV for real prices, built-in Rosstat CPI by default")
+	flag.StringVar(&base, "cpi-base", "", "Month of constant roubles for real prices in YYYY-MM format, the last month of CPI table by default")
+	flag.BoolVar(&notifyWebhooks, "notify", false, "Send parse errors, exceeded budgets and monthly totals to webhooks from config")
+	flag.BoolVar(&dryRun, "notify-dry-run", false, "Print webhook payloads to stderr instead of sending them, implies -notify")
+	flag.StringVar(&notifiedPath, "notify-state", "", "File of parse errors already sent to webhooks, so only new ones are sent, $XDG_STATE_HOME/finparser/notified by default")
+	flag.BoolVar(&warnAnomalies, "anomalies", false, "Warn about purchases with untypical prices, see anomalies command")
+	flag.BoolVar(&allEntries, "all-entries", false, "Write income and transfers along with expenses to csv, parquet, xlsx and star outputs")
 	flag.Parse()
 
 	l = log.New(os.Stderr, "", log.LstdFlags)
 
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		l.Fatalln(err)
+	}
+	if err := cfg.apply(flag.CommandLine); err != nil {
+		l.Fatalln(err)
+	}
+	// Load script describes the CSV output, other outputs have no use for it
+	if qvs != "" && (format != "csv" || flag.NArg() > 0) {
+		l.Fatalln("-qvs works with csv format only")
+	}
+	accounts, err := cfg.accounts(accountsMapping)
+	if err != nil {
+		l.Fatalln(err)
+	}
+	if cpiPath != "" {
+		f, err := os.Open(cpiPath)
+		if err != nil {
+			l.Fatalln(err)
+		}
+		cpi, err = parseCPI(f)
+		f.Close()
+		if err != nil {
+			l.Fatalf("%s: %s\n", cpiPath, err)
+		}
+	}
+	if base != "" {
+		if cpiBase, err = time.Parse(cpiMonthFormat, base); err != nil {
+			l.Fatalln(err)
+		}
+		if !cpi.covers(cpiBase) {
+			l.Fatalf("No CPI for -cpi-base %s, CPI table covers %s to %s\n",
+				base, cpi.first.Format(cpiMonthFormat), cpi.last.Format(cpiMonthFormat))
+		}
+	}
+
+	var cmd command
+	if flag.NArg() > 0 {
+		if cmd = commands[flag.Arg(0)]; cmd == nil {
+			l.Fatalf("Unknown command: %s\n", flag.Arg(0))
+		}
+	}
+
 	r := csv.NewReader(bufio.NewReader(os.Stdin))
 	records, err := r.ReadAll()
 	panicIfNotNil(err)
@@ -265,8 +668,118 @@ func main() {
 	if len(errors) > 0 {
 		l.Printf("Errors are: %s\n", errors)
 	}
+	if n := purchases.withoutCPI(); n > 0 {
+		l.Printf("Purchases out of CPI table ending %s: %d, their RealPrice is empty, pass a newer table with -cpi\n",
+			cpi.last.Format(cpiMonthFormat), n)
+	}
+	if warnAnomalies {
+		for _, a := range purchases.anomalies(ANOMALY_THRESHOLD, ANOMALY_MIN_SAMPLES) {
+			l.Printf("Anomaly: %s, row: %d, expected %s..%s by %s\n",
+				a.item.purchase.source, a.item.purchase.row, formatAmount(a.low), formatAmount(a.high), a.by)
+		}
+	}
+	if (notifyWebhooks || dryRun) && len(webhooks) == 0 {
+		l.Println("No webhooks to notify in config")
+	} else if notifyWebhooks || dryRun {
+		var w io.Writer
+		if dryRun {
+			w = os.Stderr
+		}
+		if notifiedPath == "" {
+			notifiedPath = defaultNotifiedPath()
+		}
+		notified, err := loadNotified(notifiedPath)
+		if err != nil {
+			l.Println(err)
+		}
+		payloads := purchases.webhookPayloads(newErrors(errors, notified), budgets, now())
+		if err := notify(webhooks, payloads, w); err != nil {
+			l.Println(err)
+		} else if !dryRun && notifiedPath != "" {
+			if err := saveNotified(notifiedPath, errors); err != nil {
+				l.Println(err)
+			}
+		}
+	}
 
-	w := csv.NewWriter(bufio.NewWriter(os.Stdout))
-	panicIfNotNil(w.WriteAll(purchases.toCsv()))
-	panicIfNotNil(os.Stdout.Close())
+	if cmd != nil {
+		if err := cmd(os.Stdout, purchases, flag.Args()[1:]); err != nil {
+			l.Fatalln(err)
+		}
+		return
+	}
+
+	// Expenses and refunds only by default, so that sums of Price are spending
+	entries := purchases.expenses()
+	if allEntries {
+		entries = purchases
+	}
+	switch format {
+	case "csv":
+		// Load script must use the same delimiter as the data
+		comma := ','
+		panicIfNotNil(writeOutput(out, func(w io.Writer) error {
+			cw := csv.NewWriter(w)
+			cw.Comma = comma
+			return cw.WriteAll(entries.toCsv())
+		}))
+
+		if qvs != "" {
+			f, err := os.Create(qvs)
+			panicIfNotNil(err)
+			panicIfNotNil(writeQlikScript(f, qvsFrom, comma))
+			panicIfNotNil(f.Close())
+		}
+	case "parquet":
+		panicIfNotNil(writeOutput(out, func(w io.Writer) error {
+			return entries.toTable().writeParquet(w, rowGroupSize)
+		}))
+	case "xlsx":
+		panicIfNotNil(writeOutput(out, func(w io.Writer) error {
+			return writeXlsx(w, entries, errors)
+		}))
+	case "influx":
+		panicIfNotNil(writeOutput(out, func(w io.Writer) error {
+			if influxPeriod == "" {
+				return writeInfluxPurchases(w, purchases.expenses())
+			}
+			return writeInfluxAggregates(w, purchases.expenses(), influxPeriod)
+		}))
+	case "prom":
+		write := func(w io.Writer) error {
+			return writePrometheus(w, purchases.expenses())
+		}
+		if out == "" {
+			panicIfNotNil(writeOutput(out, write))
+		} else {
+			panicIfNotNil(writeFileAtomic(out, write))
+		}
+	case "ynab", "firefly", "actual":
+		var t *table
+		switch format {
+		case "ynab":
+			t = purchases.toYnab(accounts)
+		case "firefly":
+			t = purchases.toFirefly(accounts)
+		case "actual":
+			t = purchases.toActual(accounts)
+		}
+		panicIfNotNil(writeOutput(out, t.writeCsv))
+	case "star":
+		if out == "" {
+			l.Fatalln("-out directory is required for star format")
+		}
+		switch starFormat {
+		case "csv":
+			panicIfNotNil(writeTables(out, entries.toStar(), "csv", (*table).writeCsv))
+		case "parquet":
+			panicIfNotNil(writeTables(out, entries.toStar(), "parquet", func(t *table, w io.Writer) error {
+				return t.writeParquet(w, rowGroupSize)
+			}))
+		default:
+			l.Fatalf("Unknown star schema format: %s\n", starFormat)
+		}
+	default:
+		l.Fatalf("Unknown output format: %s\n", format)
+	}
 }
@@ -575,6 +575,67 @@ func TestNewCommodity(t *testing.T) {
 	}
 }
 
+func TestNewCommodityKeepsOriginals(t *testing.T) {
+	c, err := newCommodity("Маша/Метро - проездной ($25=2250)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "транспорт", c.category)
+	assert.Equal(t, "метро", c.origCategory)
+	assert.Equal(t, "USD", c.currency)
+	assert.Equal(t, float64(25), c.amount)
+	assert.Equal(t, 2250, c.price)
+
+	c, err = newCommodity("Продукты - хлеб (20+30)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "продукты", c.origCategory)
+	assert.Equal(t, "RUB", c.currency)
+	assert.Equal(t, float64(50), c.amount)
+}
+
+func TestCategoryPaths(t *testing.T) {
+	saveGlobals(t)
+	CATEGORY_REPLACES["кафе"] = "еда"
+	CATEGORY_REPLACES["кофе"] = "кафе"
+
+	tests := []struct {
+		input               string
+		expectedPerson      string
+		expectedCategory    string
+		expectedSubcategory string
+		expectedName        string
+	}{
+		{"транспорт:метро (60)", "общие", "транспорт", "метро", "метро"},
+		{"Маша/еда/кафе - капучино (250)", "маша", "еда", "кафе", "капучино"},
+		{"еда : кафе : кофе (200)", "общие", "еда", "кафе:кофе", "кофе"},
+		{"метро (60)", "общие", "транспорт", "метро", "метро"},
+		{"кофе (200)", "общие", "еда", "кафе:кофе", "кофе"},
+		{"кафе:ужин (2000)", "общие", "еда", "кафе:ужин", "ужин"},
+		{"Маша/еда/кафе (250)", "маша", "еда", "кафе", "еда"},
+		{"Маша/еда:кафе/кофе (250)", "маша", "еда", "кафе:кофе", "кафе"},
+		{"продукты (500)", "общие", "продукты", "", "продукты"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := newCommodity(tt.input, time.Time{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPerson, c.person)
+			assert.Equal(t, tt.expectedCategory, c.category)
+			assert.Equal(t, tt.expectedSubcategory, c.subcategory)
+			assert.Equal(t, tt.expectedName, c.name)
+		})
+	}
+
+	_, err := newCommodity("Маша/: (100)", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestCategoryPathCycle(t *testing.T) {
+	saveGlobals(t)
+	CATEGORY_REPLACES["курица"] = "яйцо"
+	CATEGORY_REPLACES["яйцо"] = "курица"
+	assert.Equal(t, []string{"яйцо", "курица"}, categoryPath("курица"))
+}
+
 func TestPurchaseToArray(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -592,7 +653,7 @@ func TestPurchaseToArray(t *testing.T) {
 					price:    50,
 				},
 			},
-			expected: []string{"15.12.2023", "john", "food", "bread", "50"},
+			expected: []string{"15.12.2023", "john", "food", "bread", "50", "", "", "", "expense", "", "", "", "", "", "55"},
 		},
 		{
 			name: "purchase with zero price",
@@ -605,12 +666,26 @@ func TestPurchaseToArray(t *testing.T) {
 					price:    0,
 				},
 			},
-			expected: []string{"01.01.2023", "общие", "free", "sample", "0"},
+			expected: []string{"01.01.2023", "общие", "free", "sample", "0", "", "", "", "expense", "", "", "", "", "", "0"},
+		},
+		{
+			name: "purchase out of CPI table",
+			purchase: Purchase{
+				date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				commodity: &Commodity{
+					person:   "общие",
+					category: "food",
+					name:     "bread",
+					price:    60,
+				},
+			},
+			expected: []string{"01.02.2024", "общие", "food", "bread", "60", "", "", "", "expense", "", "", "", "", "", ""},
 		},
 	}
 
-	// Set the global date format for testing
+	saveGlobals(t)
 	df = "02.01.2006"
+	cpi = testCPI(t)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -621,8 +696,9 @@ func TestPurchaseToArray(t *testing.T) {
 }
 
 func TestPurchasesToCsv(t *testing.T) {
-	// Set the global date format for testing
+	saveGlobals(t)
 	df = "02.01.2006"
+	cpi = testCPI(t)
 
 	purchases := Purchases{
 		&Purchase{
@@ -646,8 +722,8 @@ func TestPurchasesToCsv(t *testing.T) {
 	}
 
 	expected := [][]string{
-		{"15.12.2023", "john", "food", "bread", "50"},
-		{"16.12.2023", "mary", "транспорт", "автобус", "30"},
+		{"15.12.2023", "john", "food", "bread", "50", "", "", "", "expense", "", "", "", "", "", "55"},
+		{"16.12.2023", "mary", "транспорт", "автобус", "30", "", "", "", "expense", "", "", "", "", "", "33"},
 	}
 
 	result := purchases.toCsv()
@@ -1114,7 +1190,7 @@ func TestMultiCurrencyIntegration(t *testing.T) {
 
 	// Verify CSV format correctness
 	firstRow := csvData[0]
-	assert.Len(t, firstRow, 5, "Each CSV row should have 5 columns")
+	assert.Len(t, firstRow, len(purchaseColumns), "Each CSV row should have all columns")
 	assert.Equal(t, "15.12.2023", firstRow[0])
 	// Price should be a valid integer string
 	price, err := strconv.Atoi(firstRow[4])
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Months of trailing average
+	FORECAST_WINDOW = 3
+	// Smoothing factor of exponential smoothing, weight of the latest month
+	FORECAST_ALPHA = 0.3
+	// Normal quantile of 95% prediction intervals
+	FORECAST_Z = 1.96
+)
+
+// Model predicting total of the next month from totals of previous months
+type forecastModel func(history []float64) float64
+
+var forecastModels = map[string]forecastModel{
+	"trailing": trailingAverage,
+	"seasonal": sameMonthLastYear,
+	"ets":      exponentialSmoothing,
+}
+
+// Models in order of preference when they are equally good
+var forecastModelNames = []string{"trailing", "seasonal", "ets"}
+
+func trailingAverage(history []float64) float64 {
+	n := min(len(history), FORECAST_WINDOW)
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range history[len(history)-n:] {
+		sum += v
+	}
+	return sum / float64(n)
+}
+
+// Same month a year ago, trailing average without a year of history
+func sameMonthLastYear(history []float64) float64 {
+	if len(history) < 12 {
+		return trailingAverage(history)
+	}
+	return history[len(history)-12]
+}
+
+// Simple exponential smoothing starting from the first month
+func exponentialSmoothing(history []float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	level := history[0]
+	for _, v := range history[1:] {
+		level = FORECAST_ALPHA*v + (1-FORECAST_ALPHA)*level
+	}
+	return level
+}
+
+// Root mean square error of one month ahead forecasts of history months
+// starting from the one with start index, and the number of forecasts
+func backtest(model forecastModel, history []float64, start int) (float64, int) {
+	var sum float64
+	var n int
+	for t := max(start, 1); t < len(history); t++ {
+		e := history[t] - model(history[:t])
+		sum += e * e
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return math.Sqrt(sum / float64(n)), n
+}
+
+// Month-end and year-end spending of a group with 95% prediction intervals
+type forecast struct {
+	keys                       []string
+	model                      string
+	spent                      float64 // in the month so far
+	month, monthLow, monthHigh float64
+	year, yearLow, yearHigh    float64
+	interval                   bool // false without enough history to estimate errors
+}
+
+// Forecast month-end and year-end expenses per group as of the date from totals
+// of previous months. History of a group starts from its first expense, later
+// months without expenses are zeros. Groups without previous expenses keep
+// the current pace. The "best" model is the one with the lowest backtest error.
+func (pp Purchases) forecasts(by []string, model string, date time.Time) ([]*forecast, error) {
+	var keyFuncs []func(p *Purchase) string
+	for _, d := range by {
+		if d != "person" && d != "category" && d != "name" {
+			return nil, fmt.Errorf("unknown forecast dimension: %s", d)
+		}
+		keyFuncs = append(keyFuncs, reportDimensions[d])
+	}
+	if _, ok := forecastModels[model]; !ok && model != "best" {
+		return nil, fmt.Errorf("unknown forecast model: %s", model)
+	}
+
+	current, _ := periodStart(date, "month")
+	next := current.AddDate(0, 1, 0)
+	type series struct {
+		keys   []string
+		first  time.Time // month of the first expense
+		months map[time.Time]float64
+		// Spending of previous months in total and after the day of month of the date
+		total, after float64
+	}
+	groups := map[string]*series{}
+	for _, p := range pp.expenses() {
+		if p.date.After(date) {
+			continue
+		}
+		var keys []string
+		for _, f := range keyFuncs {
+			keys = append(keys, f(p))
+		}
+		id := strings.Join(keys, "\x00")
+		s, ok := groups[id]
+		if !ok {
+			s = &series{keys: keys, first: current, months: map[time.Time]float64{}}
+			groups[id] = s
+		}
+		month, _ := periodStart(p.date, "month")
+		if month.Before(s.first) {
+			s.first = month
+		}
+		s.months[month] += float64(p.commodity.price)
+		if month.Before(current) {
+			s.total += float64(p.commodity.price)
+			if p.date.Day() > date.Day() {
+				s.after += float64(p.commodity.price)
+			}
+		}
+	}
+
+	// Share of the current month left after the date
+	days := next.Sub(current).Hours() / 24
+	calendarLeft := (days - float64(date.Day())) / days
+	// Full months left in the year after the current one
+	monthsLeft := 12 - int(current.Month())
+
+	var result []*forecast
+	for _, id := range sortedKeys(groups) {
+		s := groups[id]
+		var history []float64
+		var yearToDate float64
+		for month := s.first; month.Before(current); month = month.AddDate(0, 1, 0) {
+			history = append(history, s.months[month])
+			if month.Year() == current.Year() {
+				yearToDate += s.months[month]
+			}
+		}
+
+		// Share of monthly spending usually left after the date, monthly
+		// bills paid before the date aren't expected again
+		left := calendarLeft
+		if s.total > 0 {
+			left = s.after / s.total
+		}
+
+		f := &forecast{keys: s.keys, model: model, spent: s.months[current]}
+		var rmse float64
+		if s.total == 0 {
+			// Nothing to learn from, keep the pace of the current month
+			f.model = "pace"
+			f.month = f.spent / (1 - calendarLeft)
+			f.year = f.month * float64(monthsLeft+1)
+		} else {
+			if model == "best" {
+				best := math.Inf(1)
+				for _, name := range forecastModelNames {
+					if e, _ := backtest(forecastModels[name], history, FORECAST_WINDOW); e < best {
+						best, f.model = e, name
+					}
+				}
+			}
+			predict := forecastModels[f.model]
+			var n int
+			rmse, n = backtest(predict, history, FORECAST_WINDOW)
+			f.interval = n >= 2
+
+			f.month = f.spent + math.Max(predict(history), 0)*left
+			f.year = yearToDate + f.month
+			future := append(slices.Clone(history), f.month)
+			for range monthsLeft {
+				v := math.Max(predict(future), 0)
+				f.year += v
+				future = append(future, v)
+			}
+		}
+		if f.interval {
+			monthError := FORECAST_Z * rmse * left
+			yearError := FORECAST_Z * rmse * math.Sqrt(left*left+float64(monthsLeft))
+			f.monthLow, f.monthHigh = math.Max(f.month-monthError, f.spent), f.month+monthError
+			f.yearLow, f.yearHigh = math.Max(f.year-yearError, yearToDate+f.spent), f.year+yearError
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// Print month-end and year-end expenses forecast per group
+func forecastCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var by, model, date, output string
+	fs := newFlagSet("forecast")
+	filter.register(fs)
+	fs.StringVar(&by, "by", "category", "Comma separated dimensions: person, category or name, empty for total")
+	fs.StringVar(&model, "model", "best", "Forecast model: trailing, seasonal, ets, or best by backtest")
+	fs.StringVar(&date, "date", "", "Date to forecast from in -df format, today by default")
+	fs.StringVar(&output, "output", "table", "Output format: table, csv, json or md")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+	day := now()
+	if date != "" {
+		if day, err = time.Parse(df, date); err != nil {
+			return err
+		}
+	}
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	dimensions := splitList(by)
+	forecasts, err := pp.forecasts(dimensions, model, day)
+	if err != nil {
+		return err
+	}
+
+	t := &table{name: "forecast"}
+	for _, d := range dimensions {
+		t.columns = append(t.columns, column{strings.ToUpper(d[:1]) + d[1:], kindString})
+	}
+	t.columns = append(t.columns,
+		column{"Model", kindString},
+		column{"Spent", kindInt},
+		column{"Month", kindInt},
+		column{"Month Low", kindInt},
+		column{"Month High", kindInt},
+		column{"Year", kindInt},
+		column{"Year Low", kindInt},
+		column{"Year High", kindInt},
+	)
+	amount := func(v float64, ok bool) string {
+		if !ok {
+			return ""
+		}
+		return strconv.Itoa(int(math.Round(v)))
+	}
+	for _, f := range forecasts {
+		row := append([]string{}, f.keys...)
+		t.rows = append(t.rows, append(row,
+			f.model,
+			amount(f.spent, true),
+			amount(f.month, true),
+			amount(f.monthLow, f.interval),
+			amount(f.monthHigh, f.interval),
+			amount(f.year, true),
+			amount(f.yearLow, f.interval),
+			amount(f.yearHigh, f.interval),
+		))
+	}
+	return t.writeFormat(w, output)
+}
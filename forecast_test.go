@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Synthetic monthly totals: level, yearly seasonality with amplitude, and normal noise
+func syntheticSeries(months int, level func(t int) float64, amplitude, noise float64, seed int64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	var series []float64
+	for t := range months {
+		v := level(t) + amplitude*math.Sin(2*math.Pi*float64(t)/12) + noise*r.NormFloat64()
+		series = append(series, math.Max(v, 0))
+	}
+	return series
+}
+
+func constant(v float64) func(int) float64 {
+	return func(int) float64 {
+		return v
+	}
+}
+
+func TestForecastModels(t *testing.T) {
+	history := []float64{100, 200, 300, 400}
+	assert.Equal(t, 300.0, trailingAverage(history))
+	assert.Equal(t, 0.0, trailingAverage(nil))
+	assert.Equal(t, 300.0, sameMonthLastYear(history), "trailing average without a year of history")
+	assert.Equal(t, 3.0, sameMonthLastYear([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}))
+	assert.InDelta(t, ((100*0.7+200*0.3)*0.7+300*0.3)*0.7+400*0.3, exponentialSmoothing(history), 1e-9)
+}
+
+func TestBacktestSeasonal(t *testing.T) {
+	series := syntheticSeries(48, constant(20000), 5000, 500, 1)
+	seasonal, n := backtest(sameMonthLastYear, series, 12)
+	assert.Equal(t, 36, n)
+	trailing, _ := backtest(trailingAverage, series, 12)
+	ets, _ := backtest(exponentialSmoothing, series, 12)
+	assert.Less(t, seasonal, trailing)
+	assert.Less(t, seasonal, ets)
+	assert.Less(t, seasonal, 1500.0, "about noise of two months")
+}
+
+func TestBacktestLevelShift(t *testing.T) {
+	shift := func(t int) float64 {
+		if t < 24 {
+			return 5000
+		}
+		return 8000
+	}
+	series := syntheticSeries(36, shift, 0, 300, 2)
+	seasonal, _ := backtest(sameMonthLastYear, series, 24)
+	trailing, _ := backtest(trailingAverage, series, 24)
+	ets, _ := backtest(exponentialSmoothing, series, 24)
+	assert.Less(t, trailing, seasonal, "last year's level is gone")
+	assert.Less(t, ets, seasonal)
+}
+
+func TestForecastIntervalCoverage(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		series := syntheticSeries(36, constant(10000), 0, 1000, seed)
+		history, actual := series[:24], series[24:]
+		rmse, _ := backtest(trailingAverage, history, FORECAST_WINDOW)
+		var covered int
+		for i, v := range actual {
+			predicted := trailingAverage(append(history[:len(history):len(history)], actual[:i]...))
+			if math.Abs(v-predicted) <= FORECAST_Z*rmse {
+				covered++
+			}
+		}
+		assert.GreaterOrEqual(t, covered, 9, "seed %d: at least 75%% of months are within the interval", seed)
+	}
+}
+
+// Expenses from synthetic monthly totals, paid on the 10th day of every month
+func syntheticPurchases(series map[string][]float64, from time.Time) Purchases {
+	var pp Purchases
+	for category, totals := range series {
+		for i, v := range totals {
+			pp = append(pp, &Purchase{
+				date:      from.AddDate(0, i, 9),
+				commodity: &Commodity{person: "общие", category: category, name: category, price: int(math.Round(v))},
+			})
+		}
+	}
+	return pp
+}
+
+func TestForecasts(t *testing.T) {
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	pp := syntheticPurchases(map[string][]float64{
+		"продукты": syntheticSeries(39, constant(20000), 5000, 300, 3),
+		"кафе":     syntheticSeries(39, constant(3000), 0, 10, 4),
+	}, from)
+	pp = append(pp, &Purchase{
+		date:      time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC),
+		commodity: &Commodity{person: "маша", category: "спорт", name: "бассейн", price: 1000},
+	})
+
+	date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	forecasts, err := pp.forecasts([]string{"category"}, "best", date)
+	assert.NoError(t, err)
+	assert.Len(t, forecasts, 3)
+
+	cafe := forecasts[0]
+	assert.Equal(t, []string{"кафе"}, cafe.keys)
+	assert.Equal(t, cafe.spent, cafe.month, "paid on the 10th, nothing left in the month")
+	assert.True(t, cafe.interval)
+	assert.InDelta(t, 12*3000, cafe.year, 100)
+	assert.LessOrEqual(t, cafe.yearLow, cafe.year)
+	assert.GreaterOrEqual(t, cafe.yearHigh, cafe.year)
+
+	groceries := forecasts[1]
+	assert.Equal(t, "seasonal", groceries.model)
+	var actual float64
+	for _, v := range syntheticSeries(48, constant(20000), 5000, 300, 3)[36:] {
+		actual += v
+	}
+	assert.InDelta(t, actual, groceries.year, 0.05*actual)
+
+	sport := forecasts[2]
+	assert.Equal(t, "pace", sport.model, "no history")
+	assert.InDelta(t, 1000*31/15.0, sport.month, 1e-9)
+	assert.False(t, sport.interval)
+
+	forecasts, err = pp.forecasts(nil, "trailing", date.AddDate(0, 0, -14))
+	assert.NoError(t, err)
+	assert.Len(t, forecasts, 1)
+	assert.Equal(t, "trailing", forecasts[0].model)
+	assert.Greater(t, forecasts[0].month, 20000.0, "monthly payments are still ahead")
+
+	_, err = pp.forecasts([]string{"month"}, "best", date)
+	assert.EqualError(t, err, "unknown forecast dimension: month")
+	_, err = pp.forecasts(nil, "arima", date)
+	assert.EqualError(t, err, "unknown forecast model: arima")
+}
+
+func TestForecastGroupHistory(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pp := syntheticPurchases(map[string][]float64{"кафе": {1000, 1000, 1000, 1000, 1000, 1000}}, from)
+	pp = append(pp, syntheticPurchases(map[string][]float64{"спорт": {600, 600}}, from.AddDate(0, 4, 0))...)
+
+	forecasts, err := pp.forecasts([]string{"category"}, "trailing", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, forecasts, 2)
+	assert.Equal(t, []string{"спорт"}, forecasts[1].keys)
+	assert.Equal(t, 600.0, forecasts[1].month, "months before the first expense of the group aren't zeros")
+}
+
+func TestForecastCommand(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{{"Date", "Items"}}
+	for _, month := range []string{"10.10.2024", "10.11.2024", "10.12.2024", "10.01.2025", "10.02.2025"} {
+		records = append(records, []string{month, "кафе (1000), Маша/спорт (500)"})
+	}
+	records = append(records, []string{"03.03.2025", "кафе (300)"})
+	pp, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	var buf bytes.Buffer
+	assert.NoError(t, forecastCommand(&buf, pp, []string{"-date", "05.03.2025", "-by", "person,category", "-model", "trailing"}))
+	expected := `Person  Category  Model     Spent  Month  Month Low  Month High  Year   Year Low  Year High
+маша    спорт     trailing  0      500    500        500         6000   6000      6000
+общие   кафе      trailing  300    1300   1300       1300        13600  13600     13600
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, forecastCommand(&buf, pp, []string{"-date", "05.03.2025", "-by", "", "-output", "csv"}))
+	assert.Equal(t, "Model,Spent,Month,Month Low,Month High,Year,Year Low,Year High\ntrailing,300,1800,1800,1800,19600,19600,19600\n", buf.String())
+
+	assert.Error(t, forecastCommand(&buf, pp, []string{"-by", "week"}))
+	assert.Error(t, forecastCommand(&buf, pp, []string{"-model", "arima"}))
+	assert.Error(t, forecastCommand(&buf, pp, []string{"-output", "xml"}))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const influxMeasurement = "purchases"
+
+// Used instead of time.Now to make current month reports testable
+var now = time.Now
+
+var (
+	influxTagEscaper = strings.NewReplacer(",", "\\,", "=", "\\=", " ", "\\ ")
+	promLabelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+)
+
+// Return start of day or month the date belongs to
+func periodStart(d time.Time, period string) (time.Time, error) {
+	switch period {
+	case "day":
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location()), nil
+	case "month":
+		return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown period: %s", period)
+}
+
+type influxPoint struct {
+	person, category string
+	time             time.Time
+	amount, count    int
+}
+
+// Write every purchase as a point with person/category/name tags and
+// the price in roubles, whatever currency it was paid in.
+// Purchases of the same series on the same date get timestamps
+// a nanosecond apart, otherwise InfluxDB would keep only the last one.
+func writeInfluxPurchases(w io.Writer, pp Purchases) error {
+	bw := bufio.NewWriter(w)
+	seen := map[string]int{}
+	for _, p := range pp {
+		c := p.commodity
+		tags := fmt.Sprintf("person=%s,category=%s,name=%s",
+			influxTagEscaper.Replace(c.person), influxTagEscaper.Replace(c.category), influxTagEscaper.Replace(c.name))
+		key := tags + p.date.Format(time.DateOnly)
+		ts := p.date.UnixNano() + int64(seen[key])
+		seen[key]++
+		fmt.Fprintf(bw, "%s,%s amount_rub=%di %d\n", influxMeasurement, tags, c.price, ts)
+	}
+	return bw.Flush()
+}
+
+// Write day or month totals per person and category as points timestamped at period start
+func writeInfluxAggregates(w io.Writer, pp Purchases, period string) error {
+	points := map[string]*influxPoint{}
+	for _, p := range pp {
+		start, err := periodStart(p.date, period)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%d|%s|%s", start.UnixNano(), p.commodity.person, p.commodity.category)
+		point, ok := points[key]
+		if !ok {
+			point = &influxPoint{person: p.commodity.person, category: p.commodity.category, time: start}
+			points[key] = point
+		}
+		point.amount += p.commodity.price
+		point.count++
+	}
+
+	var sorted []*influxPoint
+	for _, point := range points {
+		sorted = append(sorted, point)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !a.time.Equal(b.time) {
+			return a.time.Before(b.time)
+		}
+		if a.person != b.person {
+			return a.person < b.person
+		}
+		return a.category < b.category
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, point := range sorted {
+		fmt.Fprintf(bw, "%s,period=%s,person=%s,category=%s amount_rub=%di,count=%di %d\n",
+			influxMeasurement, period, influxTagEscaper.Replace(point.person), influxTagEscaper.Replace(point.category),
+			point.amount, point.count, point.time.UnixNano())
+	}
+	return bw.Flush()
+}
+
+// Write current month totals per category in Prometheus text exposition format
+func writePrometheus(w io.Writer, pp Purchases) error {
+	year, month, _ := now().Date()
+	sums := map[string]int{}
+	counts := map[string]int{}
+	for _, p := range pp {
+		if p.date.Year() != year || p.date.Month() != month {
+			continue
+		}
+		sums[p.commodity.category] += p.commodity.price
+		counts[p.commodity.category]++
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# HELP finparser_month_spending_roubles Spending in current month per category.")
+	fmt.Fprintln(bw, "# TYPE finparser_month_spending_roubles gauge")
+	for _, category := range sortedKeys(sums) {
+		fmt.Fprintf(bw, "finparser_month_spending_roubles{category=\"%s\"} %d\n", promLabelEscaper.Replace(category), sums[category])
+	}
+	fmt.Fprintln(bw, "# HELP finparser_month_purchases Number of purchases in current month per category.")
+	fmt.Fprintln(bw, "# TYPE finparser_month_purchases gauge")
+	for _, category := range sortedKeys(counts) {
+		fmt.Fprintf(bw, "finparser_month_purchases{category=\"%s\"} %d\n", promLabelEscaper.Replace(category), counts[category])
+	}
+	return bw.Flush()
+}
+
+// Write file via temporary file and rename, so that node_exporter textfile
+// collector never reads a partially written file
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func influxTestPurchases() Purchases {
+	day := func(d int, m time.Month) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	return Purchases{
+		{date: day(1, 3), commodity: &Commodity{person: "маша", category: "кафе", name: "кофе", price: 200, currency: "RUB"}},
+		{date: day(1, 3), commodity: &Commodity{person: "маша", category: "кафе", name: "кофе", price: 250, currency: "RUB"}},
+		{date: day(2, 3), commodity: &Commodity{person: "общие", category: "продукты", name: "хлеб, белый", price: 50, currency: "RUB"}},
+		{date: day(5, 4), commodity: &Commodity{person: "маша", category: "кафе", name: "чай", price: 900, currency: "EUR"}},
+	}
+}
+
+func TestWriteInfluxPurchases(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeInfluxPurchases(&buf, influxTestPurchases()))
+	expected := `purchases,person=маша,category=кафе,name=кофе amount_rub=200i 1709251200000000000
+purchases,person=маша,category=кафе,name=кофе amount_rub=250i 1709251200000000001
+purchases,person=общие,category=продукты,name=хлеб\,\ белый amount_rub=50i 1709337600000000000
+purchases,person=маша,category=кафе,name=чай amount_rub=900i 1712275200000000000
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteInfluxAggregates(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeInfluxAggregates(&buf, influxTestPurchases(), "month"))
+	expected := `purchases,period=month,person=маша,category=кафе amount_rub=450i,count=2i 1709251200000000000
+purchases,period=month,person=общие,category=продукты amount_rub=50i,count=1i 1709251200000000000
+purchases,period=month,person=маша,category=кафе amount_rub=900i,count=1i 1711929600000000000
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeInfluxAggregates(&buf, influxTestPurchases(), "day"))
+	assert.Contains(t, buf.String(), "purchases,period=day,person=общие,category=продукты amount_rub=50i,count=1i 1709337600000000000\n")
+
+	assert.Error(t, writeInfluxAggregates(&buf, influxTestPurchases(), "fortnight"))
+}
+
+func TestWritePrometheus(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC) }
+
+	var buf bytes.Buffer
+	assert.NoError(t, writePrometheus(&buf, influxTestPurchases()))
+	expected := `# HELP finparser_month_spending_roubles Spending in current month per category.
+# TYPE finparser_month_spending_roubles gauge
+finparser_month_spending_roubles{category="кафе"} 450
+finparser_month_spending_roubles{category="продукты"} 50
+# HELP finparser_month_purchases Number of purchases in current month per category.
+# TYPE finparser_month_purchases gauge
+finparser_month_purchases{category="кафе"} 2
+finparser_month_purchases{category="продукты"} 1
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "finparser.prom")
+	assert.NoError(t, writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("metric 1\n"))
+		return err
+	}))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "metric 1\n", string(data))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Go types and struct tag options giving Parquet logical types for column kinds,
+// everything besides strings is optional since empty cells are allowed
+var parquetKinds = map[columnKind]struct {
+	typ     reflect.Type
+	options string
+}{
+	kindString:  {reflect.TypeOf(""), ""},
+	kindDict:    {reflect.TypeOf(""), ",dict"},
+	kindInt:     {reflect.TypeOf((*int64)(nil)), ",optional"},
+	kindDate:    {reflect.TypeOf((*int32)(nil)), ",date"}, // days since Unix epoch
+	kindDecimal: {reflect.TypeOf((*int64)(nil)), ",decimal(2:18)"},
+}
+
+// Build struct type describing table row, field order is kept in Parquet schema
+func (t *table) parquetType() reflect.Type {
+	var fields []reflect.StructField
+	for i, c := range t.columns {
+		k := parquetKinds[c.kind]
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: k.typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"%s%s"`, c.name, k.options)),
+		})
+	}
+	return reflect.StructOf(fields)
+}
+
+func parquetValue(kind columnKind, s string) (reflect.Value, error) {
+	if s == "" && kind != kindString && kind != kindDict {
+		return reflect.Zero(parquetKinds[kind].typ), nil
+	}
+	switch kind {
+	case kindInt:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&v), nil
+	case kindDate:
+		d, err := time.Parse(df, s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := int32(d.Unix() / 86400)
+		return reflect.ValueOf(&v), nil
+	case kindDecimal:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := int64(math.Round(f * 100))
+		return reflect.ValueOf(&v), nil
+	}
+	return reflect.ValueOf(s), nil
+}
+
+// Write table as Parquet file with DATE, DECIMAL and dictionary-encoded columns,
+// rowGroupSize limits number of rows per row group when positive
+func (t *table) writeParquet(w io.Writer, rowGroupSize int64) error {
+	typ := t.parquetType()
+	options := []parquet.WriterOption{parquet.SchemaOf(reflect.New(typ).Interface())}
+	if rowGroupSize > 0 {
+		options = append(options, parquet.MaxRowsPerRowGroup(rowGroupSize))
+	}
+	pw := parquet.NewWriter(w, options...)
+	for n, row := range t.rows {
+		v := reflect.New(typ)
+		for i, c := range t.columns {
+			value, err := parquetValue(c.kind, row[i])
+			if err != nil {
+				return fmt.Errorf("%s: row %d, column %s: %w", t.name, n+1, c.name, err)
+			}
+			v.Elem().Field(i).Set(value)
+		}
+		if err := pw.Write(v.Interface()); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type parquetPurchase struct {
+	Date     *int32 `parquet:"Date,date"`
+	Person   string `parquet:"Person"`
+	Category string `parquet:"Category"`
+	Name     string `parquet:"Name"`
+	Price    *int64 `parquet:"Price,decimal(2:18)"`
+}
+
+func TestWriteParquet(t *testing.T) {
+	df = "02.01.2006"
+
+	purchases := Purchases{
+		&Purchase{
+			date:      time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "john", category: "food", name: "bread", price: 50},
+		},
+		&Purchase{
+			date:      time.Date(2023, 12, 16, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "mary", category: "транспорт", name: "автобус", price: 30},
+		},
+		&Purchase{
+			date:      time.Date(2023, 12, 17, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "mary", category: "food", name: "milk", price: 90},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, purchases.toTable().writeParquet(&buf, 2))
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), f.NumRows())
+	assert.Len(t, f.RowGroups(), 2, "row groups should be limited to 2 rows")
+
+	fields := f.Schema().Fields()
+	var names []string
+	for _, field := range fields {
+		names = append(names, field.Name())
+	}
+	assert.Equal(t, purchases.toTable().header(), names, "column order should be kept")
+	assert.Equal(t, "DATE", fields[0].Type().String())
+	assert.Equal(t, "DECIMAL(18,2)", fields[4].Type().String())
+	assert.NotNil(t, fields[1].Encoding(), "person should be dictionary-encoded")
+	assert.Equal(t, "RLE_DICTIONARY", fields[1].Encoding().String())
+	assert.Equal(t, "RLE_DICTIONARY", fields[2].Encoding().String())
+
+	rows, err := parquet.Read[parquetPurchase](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, int32(19707), *rows[1].Date, "date is stored as days since epoch")
+	assert.Equal(t, "транспорт", rows[1].Category)
+	assert.Equal(t, int64(3000), *rows[1].Price, "decimal price is stored in kopecks")
+}
+
+func TestParquetValue(t *testing.T) {
+	df = "02.01.2006"
+
+	v, err := parquetValue(kindDecimal, "9.5")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(950), *v.Interface().(*int64))
+
+	v, err = parquetValue(kindInt, "")
+	assert.NoError(t, err)
+	assert.True(t, v.IsNil(), "empty cell should be null")
+
+	_, err = parquetValue(kindDate, "2023-12-15")
+	assert.Error(t, err)
+
+	v, err = parquetValue(kindString, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", v.Interface())
+}
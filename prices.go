@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Item price history key, the same name in different units are different items
+type priceItem struct {
+	name, unit string
+}
+
+// Price of an item in a month, unit price if quantity is known, average price otherwise
+type monthPrice struct {
+	spent    int
+	quantity float64
+	count    int
+}
+
+func (p *monthPrice) price() float64 {
+	if p.quantity > 0 {
+		return float64(p.spent) / p.quantity
+	}
+	return float64(p.spent) / float64(p.count)
+}
+
+// Lowercased name with single spaces and "ё" replaced with "е"
+func normalizeName(name string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(strings.ToLower(name)), " "), "ё", "е")
+}
+
+type priceHistory map[priceItem]map[time.Time]*monthPrice
+
+// Monthly prices per item over expenses, refunds are skipped
+func (pp Purchases) priceHistory() priceHistory {
+	history := priceHistory{}
+	for _, p := range pp {
+		c := p.commodity
+		if c.entryType() != EXPENSE {
+			continue
+		}
+		item := priceItem{normalizeName(c.name), c.unit}
+		if history[item] == nil {
+			history[item] = map[time.Time]*monthPrice{}
+		}
+		month, _ := periodStart(p.date, "month")
+		mp, ok := history[item][month]
+		if !ok {
+			mp = &monthPrice{}
+			history[item][month] = mp
+		}
+		mp.spent += c.price
+		mp.quantity += c.quantity
+		mp.count++
+	}
+	return history
+}
+
+// Relative change in percents, false if there's nothing to compare with
+func priceChange(history map[time.Time]*monthPrice, month, base time.Time) (float64, bool) {
+	prev, ok := history[base]
+	if !ok || prev.price() == 0 {
+		return 0, false
+	}
+	return (history[month].price()/prev.price() - 1) * 100, true
+}
+
+type inflationPoint struct {
+	month time.Time
+	index float64
+}
+
+// Chained personal inflation index, 100 in the first month. Every month is
+// compared with the previous month with purchases over items bought in both,
+// price relatives are weighted by spending on the item in the previous month.
+func (h priceHistory) inflationIndex() []inflationPoint {
+	months := map[time.Time]bool{}
+	for _, history := range h {
+		for month := range history {
+			months[month] = true
+		}
+	}
+	var sorted []time.Time
+	for month := range months {
+		sorted = append(sorted, month)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+
+	var points []inflationPoint
+	index := 100.0
+	for i, month := range sorted {
+		if i > 0 {
+			var weighted, weights float64
+			for _, history := range h {
+				prev, ok := history[sorted[i-1]]
+				cur, ok2 := history[month]
+				if !ok || !ok2 || prev.price() <= 0 {
+					continue
+				}
+				weighted += float64(prev.spent) * cur.price() / prev.price()
+				weights += float64(prev.spent)
+			}
+			if weights > 0 {
+				index *= weighted / weights
+			}
+		}
+		points = append(points, inflationPoint{month, index})
+	}
+	return points
+}
+
+func formatChange(v float64, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%+.1f%%", v)
+}
+
+// Print unit price history per item with month-over-month and year-over-year
+// changes, and the personal inflation index
+func pricesCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var names string
+	fs := newFlagSet("prices")
+	filter.register(fs)
+	fs.StringVar(&names, "item", "", "Comma separated item names, all items by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+	history := pp.priceHistory()
+
+	selected := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		if name = normalizeName(name); name != "" {
+			selected[name] = true
+		}
+	}
+	var items []priceItem
+	for item := range history {
+		if len(selected) == 0 || selected[item.name] {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].name != items[j].name {
+			return items[i].name < items[j].name
+		}
+		return items[i].unit < items[j].unit
+	})
+
+	t := &table{columns: []column{
+		{"Item", kindString},
+		{"Unit", kindString},
+		{"Month", kindString},
+		{"Price", kindDecimal},
+		{"MoM", kindString},
+		{"YoY", kindString},
+	}}
+	for _, item := range items {
+		var months []time.Time
+		for month := range history[item] {
+			months = append(months, month)
+		}
+		sort.Slice(months, func(i, j int) bool {
+			return months[i].Before(months[j])
+		})
+		for _, month := range months {
+			t.rows = append(t.rows, []string{
+				item.name,
+				item.unit,
+				month.Format("2006-01"),
+				fmt.Sprintf("%.2f", history[item][month].price()),
+				formatChange(priceChange(history[item], month, month.AddDate(0, -1, 0))),
+				formatChange(priceChange(history[item], month, month.AddDate(-1, 0, 0))),
+			})
+		}
+	}
+	if err := t.writeText(w); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	basket := history
+	if len(selected) > 0 {
+		basket = priceHistory{}
+		for _, item := range items {
+			basket[item] = history[item]
+		}
+	}
+	t = &table{columns: []column{{"Month", kindString}, {"Index", kindDecimal}}}
+	for _, point := range basket.inflationIndex() {
+		t.rows = append(t.rows, []string{point.month.Format("2006-01"), fmt.Sprintf("%.1f", point.index)})
+	}
+	return t.writeText(w)
+}
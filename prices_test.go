@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pricesTestPurchases(t *testing.T) Purchases {
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"03.01.2024", "хлеб (50), бензин 40л (2000), кафе (1500)"},
+		{"10.01.2024", "Хлеб (50), возврат (-100)"},
+		{"05.02.2024", "хлеб (55), бензин 20л (52.5/л), молоко 930мл (93)"},
+		{"05.02.2025", "хлёб (66)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	return purchases
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "хлеб бородинский", normalizeName("  Хлёб   Бородинский "))
+}
+
+func TestPriceHistory(t *testing.T) {
+	saveGlobals(t)
+	history := pricesTestPurchases(t).priceHistory()
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	bread := history[priceItem{"хлеб", ""}]
+	assert.Len(t, bread, 3)
+	assert.Equal(t, 50.0, bread[jan].price())
+	assert.Equal(t, 55.0, bread[feb].price())
+	fuel := history[priceItem{"бензин", "л"}]
+	assert.Equal(t, 50.0, fuel[jan].price())
+	assert.Equal(t, 52.5, fuel[feb].price())
+	assert.NotContains(t, history, priceItem{"возврат", ""}, "refunds are skipped")
+
+	change, ok := priceChange(bread, feb, jan)
+	assert.True(t, ok)
+	assert.InDelta(t, 10, change, 1e-9)
+	_, ok = priceChange(bread, jan, jan.AddDate(0, -1, 0))
+	assert.False(t, ok)
+}
+
+func TestInflationIndex(t *testing.T) {
+	saveGlobals(t)
+	points := pricesTestPurchases(t).priceHistory().inflationIndex()
+	assert.Len(t, points, 3)
+	assert.Equal(t, 100.0, points[0].index)
+	// хлеб +10% weighted by 100, бензин +5% by 2000, кафе isn't bought in February
+	assert.InDelta(t, 100*(100*1.1+2000*1.05)/2100, points[1].index, 1e-9)
+	assert.InDelta(t, points[1].index*1.2, points[2].index, 1e-9)
+}
+
+func TestPricesCommand(t *testing.T) {
+	saveGlobals(t)
+	var buf bytes.Buffer
+	assert.NoError(t, pricesCommand(&buf, pricesTestPurchases(t), []string{"-item", "Хлеб"}))
+	expected := `Item  Unit  Month    Price  MoM     YoY
+хлеб        2024-01  50.00
+хлеб        2024-02  55.00  +10.0%
+хлеб        2025-02  66.00          +20.0%
+
+Month    Index
+2024-01  100.0
+2024-02  110.0
+2025-02  132.0
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, pricesCommand(&buf, pricesTestPurchases(t), []string{"-to", "29.02.2024"}))
+	assert.Contains(t, buf.String(), "бензин  л     2024-02  52.50    +5.0%\n")
+	assert.Contains(t, buf.String(), "молоко  л     2024-02  100.00\n")
+	assert.Contains(t, buf.String(), "2024-02  105.2\n")
+}
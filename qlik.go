@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Go layout elements and their Qlik counterparts, longest first so that
+// "2006" wins over "2" and "January" over "Jan"
+var qlikDateTokens = []struct {
+	layout, qlik string
+}{
+	{"January", "MMMM"},
+	{"Monday", "WWWW"},
+	{"2006", "YYYY"},
+	{"Jan", "MMM"},
+	{"Mon", "WWW"},
+	{"01", "MM"},
+	{"02", "DD"},
+	{"_2", "D"},
+	{"03", "hh"},
+	{"04", "mm"},
+	{"05", "ss"},
+	{"06", "YY"},
+	{"15", "hh"},
+	{"PM", "TT"},
+	{"pm", "tt"},
+	{"1", "M"},
+	{"2", "D"},
+	{"3", "h"},
+	{"4", "m"},
+	{"5", "s"},
+}
+
+// Convert Golang date layout like "02.01.2006" to Qlik format like "DD.MM.YYYY"
+func qlikDateFormat(layout string) string {
+	var sb strings.Builder
+	for len(layout) > 0 {
+		matched := false
+		for _, t := range qlikDateTokens {
+			if strings.HasPrefix(layout, t.layout) {
+				sb.WriteString(t.qlik)
+				layout = layout[len(t.layout):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			sb.WriteByte(layout[0])
+			layout = layout[1:]
+		}
+	}
+	return sb.String()
+}
+
+// Quote value for INLINE table if it contains delimiter or closing bracket
+func qlikInlineValue(s string) string {
+	if strings.ContainsAny(s, ",]\"") {
+		return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+	}
+	return s
+}
+
+// Write Qlik load script for the CSV produced by finparser.
+// Field list follows purchaseColumns, date interpretation follows df.
+// Categories in CSV are already replaced, so the mapping table generated
+// from CATEGORY_REPLACES is only for other sources loaded along with it.
+func writeQlikScript(w io.Writer, from string, delimiter rune) error {
+	dateFormat := qlikDateFormat(df)
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "// Generated by finparser for date format %q, do not edit by hand\n", df)
+	fmt.Fprintf(bw, "SET DateFormat='%s';\n\n", dateFormat)
+
+	keys := sortedKeys(CATEGORY_REPLACES)
+	fmt.Fprintln(bw, "CategoryReplaces:")
+	fmt.Fprintln(bw, "MAPPING LOAD * INLINE [")
+	fmt.Fprintln(bw, "From, To")
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%s, %s\n", qlikInlineValue(k), qlikInlineValue(CATEGORY_REPLACES[k]))
+	}
+	fmt.Fprintln(bw, "];")
+	fmt.Fprintln(bw)
+
+	fmt.Fprintln(bw, "Purchases:")
+	fmt.Fprintln(bw, "LOAD")
+	for i, col := range purchaseColumns {
+		field := fmt.Sprintf("@%d", i+1)
+		switch col.name {
+		case "Date":
+			field = fmt.Sprintf("Date(Date#(%s, '%s'))", field, dateFormat)
+		}
+		sep := ","
+		if i == len(purchaseColumns)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(bw, "\t%s as %s%s\n", field, col.name, sep)
+	}
+	fmt.Fprintf(bw, "FROM [%s]\n", from)
+	fmt.Fprintf(bw, "(txt, codepage is 65001, no labels, delimiter is '%c', msq);\n", delimiter)
+
+	return bw.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQlikDateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string
+		expected string
+	}{
+		{
+			name:     "default format",
+			layout:   "02.01.2006",
+			expected: "DD.MM.YYYY",
+		},
+		{
+			name:     "ISO format",
+			layout:   "2006-01-02",
+			expected: "YYYY-MM-DD",
+		},
+		{
+			name:     "US format with short year",
+			layout:   "1/2/06",
+			expected: "M/D/YY",
+		},
+		{
+			name:     "month names",
+			layout:   "2 Jan 2006",
+			expected: "D MMM YYYY",
+		},
+		{
+			name:     "date and time",
+			layout:   "02.01.2006 15:04:05",
+			expected: "DD.MM.YYYY hh:mm:ss",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, qlikDateFormat(tt.layout))
+		})
+	}
+}
+
+func TestQlikInlineValue(t *testing.T) {
+	assert.Equal(t, "транспорт", qlikInlineValue("транспорт"))
+	assert.Equal(t, "\"a, b\"", qlikInlineValue("a, b"))
+	assert.Equal(t, "\"x]\"", qlikInlineValue("x]"))
+}
+
+func TestWriteQlikScript(t *testing.T) {
+	df = "02.01.2006"
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeQlikScript(&buf, "lib://finparser/purchases.csv", ','))
+	script := buf.String()
+
+	assert.Contains(t, script, "SET DateFormat='DD.MM.YYYY';")
+	assert.Contains(t, script, "MAPPING LOAD * INLINE [\nFrom, To\n")
+	assert.Contains(t, script, "автобус, транспорт\n")
+	assert.Contains(t, script, "интернет, связь\n")
+	assert.Contains(t, script, "\tDate(Date#(@1, 'DD.MM.YYYY')) as Date,\n")
+	assert.Contains(t, script, "\t@3 as Category,\n", "categories are replaced by finparser already")
+	assert.NotContains(t, script, "ApplyMap")
+	assert.Contains(t, script, "\t@5 as Price,\n")
+	assert.Contains(t, script, "FROM [lib://finparser/purchases.csv]\n")
+	assert.Contains(t, script, "(txt, codepage is 65001, no labels, delimiter is ',', msq);")
+
+	// Every output column must be loaded
+	for i, col := range purchaseColumns {
+		assert.Contains(t, script, " as "+col.name, "column %d", i)
+	}
+}
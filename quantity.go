@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unit of quantity with its base unit and factor to it
+type unit struct {
+	base   string
+	factor float64
+}
+
+// Units by name as written, grams and millilitres are normalised to kilograms and litres
+var units = map[string]unit{
+	"кг": {"кг", 1},
+	"г":  {"кг", 0.001},
+	"гр": {"кг", 0.001},
+	"л":  {"л", 1},
+	"мл": {"л", 0.001},
+	"шт": {"шт", 1},
+	"kg": {"кг", 1},
+	"g":  {"кг", 0.001},
+	"l":  {"л", 1},
+	"ml": {"л", 0.001},
+}
+
+// Quantity as written, like 500 and "г"
+type measure struct {
+	value float64
+	unit  string
+}
+
+// Quantity in base unit, rounded to get rid of float errors like 0.30000000000000004
+func (m *measure) normalize() (float64, string) {
+	u := units[m.unit]
+	return math.Round(m.value*u.factor*1e6) / 1e6, u.base
+}
+
+// Unit alternation for regexps, longest names first so that "г" doesn't shadow "гр"
+func unitsAlternation() string {
+	names := sortedKeys(units)
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	return strings.Join(names, "|")
+}
+
+// Cut trailing quantity like "40л" or "500 г" off the description
+func cutQuantity(desc string) (string, *measure) {
+	tokens := reQuantity.FindStringSubmatch(desc)
+	if tokens == nil {
+		return desc, nil
+	}
+	value, err := strconv.ParseFloat(tokens[1], 64)
+	if err != nil || value <= 0 {
+		return desc, nil
+	}
+	// Name separator is left alone in "яблоки - 2кг"
+	desc = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.TrimSuffix(desc, tokens[0])), " -"))
+	return desc, &measure{value, strings.ToLower(tokens[2])}
+}
+
+// Cut currency symbol prefix off the price, the default currency if there's none
+func cutCurrencySymbol(s string) (string, string, error) {
+	for i, r := range s {
+		if r >= '0' && r <= '9' {
+			if i == 0 {
+				return DEFAULT_CURRENCY, s, nil
+			}
+			code, ok := currencySymbols[strings.TrimSpace(s[:i])]
+			if !ok {
+				return "", "", fmt.Errorf("unknown currency: %s", s[:i])
+			}
+			return code, s[i:], nil
+		}
+	}
+	return "", "", fmt.Errorf("invalid price: %s", s)
+}
+
+// Parse unit price like "56.5/л" for the quantity from description, or "2кг × 150",
+// or "× 150" for the quantity from description. Returns total in currency, currency
+// code and the quantity, ok is false if it's not a unit price at all.
+func parseUnitPrice(s string, m *measure) (float64, string, *measure, bool, error) {
+	var strPrice string
+	var count float64 // number of units the price is given for
+	if tokens := rePerUnit.FindStringSubmatch(s); tokens != nil {
+		strPrice = tokens[1]
+		priceUnit, ok := units[strings.ToLower(tokens[2])]
+		if !ok {
+			return 0, "", nil, true, fmt.Errorf("unknown unit: %s", tokens[2])
+		}
+		if m == nil {
+			return 0, "", nil, true, fmt.Errorf("unit price without quantity: %s", s)
+		}
+		if units[m.unit].base != priceUnit.base {
+			return 0, "", nil, true, fmt.Errorf("quantity in %s priced per %s", m.unit, tokens[2])
+		}
+		count = m.value * units[m.unit].factor / priceUnit.factor
+	} else if tokens := reTimes.FindStringSubmatch(s); tokens != nil {
+		strPrice = tokens[3]
+		if tokens[1] != "" {
+			if m != nil {
+				return 0, "", nil, true, fmt.Errorf("quantity is given twice: %s", s)
+			}
+			value, err := strconv.ParseFloat(tokens[1], 64)
+			if err != nil {
+				return 0, "", nil, true, err
+			}
+			m = &measure{value, strings.ToLower(tokens[2])}
+		} else if m == nil {
+			return 0, "", nil, true, fmt.Errorf("unit price without quantity: %s", s)
+		}
+		count = m.value
+	} else {
+		return 0, "", nil, false, nil
+	}
+
+	code, strPrice, err := cutCurrencySymbol(strings.TrimSpace(strPrice))
+	if err != nil {
+		return 0, "", nil, true, err
+	}
+	price, err := strconv.ParseFloat(strPrice, 64)
+	if err != nil {
+		return 0, "", nil, true, err
+	}
+	return count * price, code, m, true, nil
+}
+
+// Price in roubles per base unit, zero if quantity is unknown
+func (c *Commodity) unitPrice() float64 {
+	if c.quantity == 0 {
+		return 0
+	}
+	return math.Round(float64(c.price)/c.quantity*100) / 100
+}
+
+// Quantity or unit price for output, empty if unknown
+func formatQuantity(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutQuantity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		measure  *measure
+	}{
+		{"бензин", "бензин", nil},
+		{"бензин 40л", "бензин", &measure{40, "л"}},
+		{"Продукты - сыр 350 Г", "Продукты - сыр", &measure{350, "г"}},
+		{"вода 0.5л", "вода", &measure{0.5, "л"}},
+		{"яйца 10шт", "яйца", &measure{10, "шт"}},
+		{"40л", "40л", nil},
+		{"витамин d3", "витамин d3", nil},
+		{"масло 5w40", "масло 5w40", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			desc, m := cutQuantity(tt.input)
+			assert.Equal(t, tt.expected, desc)
+			assert.Equal(t, tt.measure, m)
+		})
+	}
+}
+
+func TestParseUnitPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		measure  *measure
+		total    float64
+		currency string
+		expected *measure
+		notUnit  bool
+		wantErr  bool
+	}{
+		{name: "per unit", input: "56.5/л", measure: &measure{40, "л"}, total: 2260, currency: "RUB", expected: &measure{40, "л"}},
+		{name: "grams per kilogram", input: "900/кг", measure: &measure{250, "г"}, total: 225, currency: "RUB", expected: &measure{250, "г"}},
+		{name: "foreign currency", input: "€2/л", measure: &measure{30, "л"}, total: 60, currency: "EUR", expected: &measure{30, "л"}},
+		{name: "times", input: "2кг × 150", total: 300, currency: "RUB", expected: &measure{2, "кг"}},
+		{name: "asterisk", input: "0.5кг*$4", total: 2, currency: "USD", expected: &measure{0.5, "кг"}},
+		{name: "times description quantity", input: "× 150", measure: &measure{3, "шт"}, total: 450, currency: "RUB", expected: &measure{3, "шт"}},
+		{name: "plain price", input: "2260", notUnit: true},
+		{name: "expression", input: "40*56", notUnit: true},
+		{name: "division", input: "100/2", notUnit: true},
+		{name: "no quantity", input: "56.5/л", wantErr: true},
+		{name: "unit mismatch", input: "56.5/кг", measure: &measure{40, "л"}, wantErr: true},
+		{name: "unknown unit", input: "56.5/бочка", measure: &measure{40, "л"}, wantErr: true},
+		{name: "quantity twice", input: "2кг × 150", measure: &measure{1, "кг"}, wantErr: true},
+		{name: "unknown currency", input: "¥2/л", measure: &measure{30, "л"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, currency, m, ok, err := parseUnitPrice(tt.input, tt.measure)
+			if tt.wantErr {
+				assert.True(t, ok)
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, !tt.notUnit, ok)
+			if ok {
+				assert.InDelta(t, tt.total, total, 1e-9)
+				assert.Equal(t, tt.currency, currency)
+				assert.Equal(t, tt.expected, m)
+			}
+		})
+	}
+}
+
+func TestNewCommodityQuantity(t *testing.T) {
+	saveGlobals(t)
+
+	tests := []struct {
+		input     string
+		name      string
+		price     int
+		quantity  float64
+		unit      string
+		unitPrice float64
+	}{
+		{"бензин 40л (56.5/л)", "бензин", 2260, 40, "л", 56.5},
+		{"бензин 40л (2260)", "бензин", 2260, 40, "л", 56.5},
+		{"яблоки - 2кг × 150", "яблоки", 300, 2, "кг", 150},
+		{"яблоки (2кг × 150)", "яблоки", 300, 2, "кг", 150},
+		{"сыр 300г (900/кг)", "сыр", 270, 0.3, "кг", 900},
+		{"молоко 930мл (93)", "молоко", 93, 0.93, "л", 100},
+		{"хлеб (50)", "хлеб", 50, 0, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := newCommodity(tt.input, time.Time{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.name, c.name)
+			assert.Equal(t, tt.price, c.price)
+			assert.Equal(t, tt.quantity, c.quantity)
+			assert.Equal(t, tt.unit, c.unit)
+			assert.Equal(t, tt.unitPrice, c.unitPrice())
+		})
+	}
+
+	_, err := newCommodity("бензин (56.5/л)", time.Time{})
+	assert.Error(t, err)
+
+	c, err := newCommodity("Маша+Петя/сыр 300г (900/кг)", time.Time{})
+	assert.NoError(t, err)
+	for _, part := range c.split() {
+		assert.Equal(t, 0.15, part.quantity)
+		assert.Equal(t, 135, part.price)
+	}
+}
+
+func TestPurchaseToArrayQuantity(t *testing.T) {
+	df = "02.01.2006"
+	p := Purchase{
+		date:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		commodity: &Commodity{person: "общие", category: "бензин", name: "бензин", price: 2260, quantity: 40, unit: "л"},
+	}
+	row := p.toArray()
+	assert.Equal(t, []string{"40", "л", "56.5"}, row[len(row)-4:len(row)-1])
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimum number of payments to detect a recurring one
+const RECURRING_MIN_COUNT = 3
+
+// Relative change of amount below which it's considered the same, like
+// rounding of conversion to roubles or a few kopecks of taxes
+const RECURRING_PRICE_TOLERANCE = 0.01
+
+// Period of recurring payments with tolerance of intervals in days
+type cadence struct {
+	name      string
+	days      float64
+	tolerance float64
+	next      func(t time.Time) time.Time
+}
+
+var cadences = []cadence{
+	{"weekly", 7, 2, func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }},
+	{"monthly", 30.44, 5, func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"yearly", 365.25, 15, func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// Number of periods in the interval, zero if it isn't a multiple of the period
+func (c *cadence) periods(days float64) int {
+	n := int(math.Round(days / c.days))
+	if n < 1 || math.Abs(days-float64(n)*c.days) > c.tolerance*math.Sqrt(float64(n)) {
+		return 0
+	}
+	return n
+}
+
+// Recurring payment detected from expenses with the same name, category and
+// currency, paid with regular intervals and similar amounts
+type recurring struct {
+	items    []*expenseItem // ordered by date
+	cadence  *cadence
+	missed   int // payments skipped between the first and the last one
+	next     time.Time
+	overdue  bool // no payment for the period after the next date
+	previous float64
+	amount   float64
+}
+
+func (r *recurring) last() *Purchase {
+	return r.items[len(r.items)-1].purchase
+}
+
+// Price changed since the previous payment
+func (r *recurring) changed() bool {
+	return math.Abs(r.amount-r.previous) > RECURRING_PRICE_TOLERANCE*r.previous
+}
+
+func (r *recurring) status() string {
+	var statuses []string
+	if r.overdue {
+		statuses = append(statuses, "overdue")
+	}
+	if r.missed > 0 {
+		statuses = append(statuses, "missed")
+	}
+	if r.changed() {
+		statuses = append(statuses, "changed")
+	}
+	if len(statuses) == 0 {
+		return "ok"
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// Detect recurring payments as of the date. The cadence is the one matching
+// the median interval between payments, every interval must be a multiple of
+// it, multiples mean missed payments. Amounts in original currency must be
+// within a half of their median, so changed prices are still detected.
+func (pp Purchases) recurring(date time.Time, minCount int) []*recurring {
+	groups := map[string][]*expenseItem{}
+	for _, i := range pp.expenseItems() {
+		c := i.purchase.commodity
+		if i.purchase.date.After(date) {
+			continue
+		}
+		key := strings.Join([]string{normalizeName(c.name), c.category, c.currency}, "\x00")
+		groups[key] = append(groups[key], i)
+	}
+
+	var result []*recurring
+	for _, key := range sortedKeys(groups) {
+		items := groups[key]
+		if len(items) < minCount {
+			continue
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].purchase.date.Before(items[j].purchase.date)
+		})
+
+		var intervals, amounts []float64
+		for i, item := range items {
+			amounts = append(amounts, item.amount)
+			if i > 0 {
+				intervals = append(intervals, items[i].purchase.date.Sub(items[i-1].purchase.date).Hours()/24)
+			}
+		}
+		r := &recurring{items: items}
+		for i := range cadences {
+			if cadences[i].periods(median(intervals)) == 1 {
+				r.cadence = &cadences[i]
+			}
+		}
+		if r.cadence == nil {
+			continue
+		}
+		regular := true
+		for _, days := range intervals {
+			n := r.cadence.periods(days)
+			if n == 0 {
+				regular = false
+				break
+			}
+			r.missed += n - 1
+		}
+		typical := median(amounts)
+		for _, amount := range amounts {
+			if math.Abs(amount-typical) > typical/2 {
+				regular = false
+			}
+		}
+		if !regular {
+			continue
+		}
+
+		r.amount = items[len(items)-1].amount
+		r.previous = items[len(items)-2].amount
+		r.next = r.cadence.next(r.last().date)
+		r.overdue = date.Sub(r.next).Hours()/24 > r.cadence.tolerance
+		result = append(result, r)
+	}
+	return result
+}
+
+// Print recurring payments with expected next date and amount
+func recurringCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var date, output string
+	var minCount int
+	fs := newFlagSet("recurring")
+	filter.register(fs)
+	fs.StringVar(&date, "date", "", "Date to check payments on in -df format, today by default")
+	fs.IntVar(&minCount, "min-count", RECURRING_MIN_COUNT, "Minimum number of payments to detect a recurring one")
+	fs.StringVar(&output, "output", "table", "Output format: table, csv, json or md")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if minCount < 3 {
+		return fmt.Errorf("min-count must be at least 3")
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+	day := now()
+	if date != "" {
+		if day, err = time.Parse(df, date); err != nil {
+			return err
+		}
+	}
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	t := &table{name: "recurring", columns: []column{
+		{"Name", kindString},
+		{"Category", kindString},
+		{"Cadence", kindString},
+		{"Count", kindInt},
+		{"Last", kindDate},
+		{"Next", kindDate},
+		{"Amount", kindDecimal},
+		{"Previous", kindDecimal},
+		{"Currency", kindString},
+		{"Missed", kindInt},
+		{"Status", kindString},
+	}}
+	for _, r := range pp.recurring(day, minCount) {
+		c := r.last().commodity
+		var previous string
+		if r.changed() {
+			previous = formatAmount(r.previous)
+		}
+		t.rows = append(t.rows, []string{
+			c.name,
+			c.category,
+			r.cadence.name,
+			strconv.Itoa(len(r.items)),
+			r.last().date.Format(df),
+			r.next.Format(df),
+			formatAmount(r.amount),
+			previous,
+			c.currency,
+			strconv.Itoa(r.missed),
+			r.status(),
+		})
+	}
+	return t.writeFormat(w, output)
+}
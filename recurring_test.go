@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recurringTestPurchases(t *testing.T) Purchases {
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"05.01.2025", "связь - интернет (600), подписки - кино ($5=450), продукты - хлеб (50)"},
+		{"12.01.2025", "Маша+Петя/спорт - бассейн (1000), продукты - хлеб (150)"},
+		{"19.01.2025", "Маша+Петя/спорт - бассейн (1000), продукты - хлеб (55)"},
+		{"26.01.2025", "Маша+Петя/спорт - бассейн (1000)"},
+		{"02.02.2025", "Маша+Петя/спорт - бассейн (1000), такси (300)"},
+		{"05.02.2025", "связь - интернет (600), подписки - кино ($5=470), такси (500)"},
+		{"05.03.2025", "связь - интернет (600), подписки - кино ($5=460), такси (400)"},
+		{"05.05.2025", "связь - интернет (650)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	return purchases
+}
+
+func TestCadencePeriods(t *testing.T) {
+	monthly := &cadences[1]
+	assert.Equal(t, 1, monthly.periods(28))
+	assert.Equal(t, 1, monthly.periods(31))
+	assert.Equal(t, 2, monthly.periods(59))
+	assert.Equal(t, 0, monthly.periods(45))
+	assert.Equal(t, 0, monthly.periods(10))
+	assert.Equal(t, 1, cadences[2].periods(366))
+}
+
+func TestRecurring(t *testing.T) {
+	saveGlobals(t)
+	date := time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC)
+	found := recurringTestPurchases(t).recurring(date, RECURRING_MIN_COUNT)
+	var names []string
+	for _, r := range found {
+		names = append(names, r.last().commodity.name)
+	}
+	assert.Equal(t, []string{"бассейн", "интернет", "кино"}, names, "хлеб amounts and такси intervals aren't regular")
+
+	pool := found[0]
+	assert.Equal(t, "weekly", pool.cadence.name)
+	assert.Len(t, pool.items, 4)
+	assert.Equal(t, 1000.0, pool.amount, "shared payments are joined back")
+	assert.Equal(t, time.Date(2025, 2, 9, 0, 0, 0, 0, time.UTC), pool.next)
+	assert.Equal(t, "overdue", pool.status())
+
+	internet := found[1]
+	assert.Equal(t, "monthly", internet.cadence.name)
+	assert.Equal(t, 1, internet.missed, "April is missed")
+	assert.Equal(t, time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC), internet.next)
+	assert.True(t, internet.changed())
+	assert.Equal(t, "missed, changed", internet.status())
+
+	movies := found[2]
+	assert.Equal(t, 5.0, movies.amount, "compared in original currency")
+	assert.False(t, movies.changed(), "rouble price follows the rate")
+	assert.Equal(t, "overdue", movies.status())
+
+	found = recurringTestPurchases(t).recurring(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), RECURRING_MIN_COUNT)
+	assert.Len(t, found, 3)
+	assert.Equal(t, "ok", found[1].status(), "later payments are skipped")
+	assert.Empty(t, recurringTestPurchases(t).recurring(date, 5))
+}
+
+func TestRecurringDuplicateItems(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	var records [][]string
+	records = append(records, []string{"Date", "Items"})
+	for _, date := range []string{"05.01.2025", "05.02.2025", "05.03.2025", "05.04.2025"} {
+		records = append(records, []string{date, "Маша+Петя/подписки - кино (500), Маша+Петя/подписки - кино (500), связь - интернет (600)"})
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	found := purchases.recurring(time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC), RECURRING_MIN_COUNT)
+	assert.Len(t, found, 1, "two payments a day aren't a single doubled one")
+	assert.Equal(t, "интернет", found[0].last().commodity.name)
+	assert.Equal(t, 600.0, found[0].amount)
+	assert.Equal(t, "ok", found[0].status())
+}
+
+func TestRecurringCommand(t *testing.T) {
+	saveGlobals(t)
+	var buf bytes.Buffer
+	assert.NoError(t, recurringCommand(&buf, recurringTestPurchases(t), []string{"-date", "20.05.2025"}))
+	expected := `Name      Category  Cadence  Count  Last        Next        Amount  Previous  Currency  Missed  Status
+бассейн   спорт     weekly   4      02.02.2025  09.02.2025  1000              RUB       0       overdue
+интернет  связь     monthly  4      05.05.2025  05.06.2025  650     600       RUB       1       missed, changed
+кино      подписки  monthly  3      05.03.2025  05.04.2025  5                 USD       0       overdue
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, recurringCommand(&buf, recurringTestPurchases(t), []string{"-date", "20.05.2025", "-tag", "нет", "-output", "csv"}))
+	assert.Equal(t, "Name,Category,Cadence,Count,Last,Next,Amount,Previous,Currency,Missed,Status\n", buf.String())
+
+	assert.Error(t, recurringCommand(&buf, recurringTestPurchases(t), []string{"-min-count", "2"}))
+	assert.Error(t, recurringCommand(&buf, recurringTestPurchases(t), []string{"-date", "2025-05-20"}))
+}
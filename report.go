@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grouping dimensions of the report, periods are formatted to sort chronologically
+var reportDimensions = map[string]func(p *Purchase) string{
+	"day": func(p *Purchase) string {
+		return p.date.Format("2006-01-02")
+	},
+	"week": func(p *Purchase) string {
+		year, week := p.date.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	},
+	"month": func(p *Purchase) string {
+		return p.date.Format("2006-01")
+	},
+	"quarter": func(p *Purchase) string {
+		return fmt.Sprintf("%d-Q%d", p.date.Year(), (int(p.date.Month())+2)/3)
+	},
+	"year": func(p *Purchase) string {
+		return p.date.Format("2006")
+	},
+	"person": func(p *Purchase) string {
+		return p.commodity.person
+	},
+	"category": func(p *Purchase) string {
+		return p.commodity.category
+	},
+	"name": func(p *Purchase) string {
+		return p.commodity.name
+	},
+}
+
+// Report metrics in the order of output columns
+var reportMetrics = []column{
+	{"total", kindDecimal},
+	{"count", kindInt},
+	{"avg", kindDecimal},
+	{"share", kindDecimal},
+}
+
+type reportGroup struct {
+	keys         []string
+	total, count int
+}
+
+// Split comma separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Aggregate expenses by dimensions, groups are sorted by keys
+func (pp Purchases) report(by []string) ([]*reportGroup, error) {
+	var keyFuncs []func(p *Purchase) string
+	for _, d := range by {
+		f, ok := reportDimensions[d]
+		if !ok {
+			return nil, fmt.Errorf("unknown report dimension: %s", d)
+		}
+		keyFuncs = append(keyFuncs, f)
+	}
+
+	groups := map[string]*reportGroup{}
+	for _, p := range pp.expenses() {
+		var keys []string
+		for _, f := range keyFuncs {
+			keys = append(keys, f(p))
+		}
+		id := strings.Join(keys, "\x00")
+		g, ok := groups[id]
+		if !ok {
+			g = &reportGroup{keys: keys}
+			groups[id] = g
+		}
+		g.total += p.commodity.price
+		g.count++
+	}
+
+	var result []*reportGroup
+	for _, id := range sortedKeys(groups) {
+		result = append(result, groups[id])
+	}
+	return result, nil
+}
+
+// Report table with a column per dimension and metric. Share is
+// a percentage of the total of all groups.
+func reportTable(groups []*reportGroup, by, metrics []string) (*table, error) {
+	t := &table{name: "report"}
+	title := func(s string) string {
+		return strings.ToUpper(s[:1]) + s[1:]
+	}
+	for _, d := range by {
+		t.columns = append(t.columns, column{title(d), kindString})
+	}
+	for _, m := range metrics {
+		i := slices.IndexFunc(reportMetrics, func(c column) bool {
+			return c.name == m
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("unknown report metric: %s", m)
+		}
+		t.columns = append(t.columns, column{title(m), reportMetrics[i].kind})
+	}
+
+	var grandTotal int
+	for _, g := range groups {
+		grandTotal += g.total
+	}
+	for _, g := range groups {
+		row := append([]string{}, g.keys...)
+		for _, m := range metrics {
+			var value string
+			switch m {
+			case "total":
+				value = strconv.Itoa(g.total)
+			case "count":
+				value = strconv.Itoa(g.count)
+			case "avg":
+				value = fmt.Sprintf("%.2f", float64(g.total)/float64(g.count))
+			case "share":
+				if grandTotal != 0 {
+					value = fmt.Sprintf("%.1f", float64(g.total)*100/float64(grandTotal))
+				}
+			}
+			row = append(row, value)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+// Print expenses aggregated by dimensions
+func reportCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	var by, metrics, output, order string
+	fs := newFlagSet("report")
+	filter.register(fs)
+	fs.StringVar(&by, "by", "month,category", "Comma separated dimensions: day, week, month, quarter, year, person, category or name")
+	fs.StringVar(&metrics, "metrics", "total,count", "Comma separated metrics: total, count, avg or share")
+	fs.StringVar(&output, "output", "table", "Output format: table, csv, json or md")
+	fs.StringVar(&order, "sort", "key", "Sort groups by key, or by total descending")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+
+	dimensions := splitList(by)
+	groups, err := pp.report(dimensions)
+	if err != nil {
+		return err
+	}
+	switch order {
+	case "key":
+	case "total":
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].total > groups[j].total
+		})
+	default:
+		return fmt.Errorf("unknown sort order: %s", order)
+	}
+	t, err := reportTable(groups, dimensions, splitList(metrics))
+	if err != nil {
+		return err
+	}
+	return t.writeFormat(w, output)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reportTestPurchases(t *testing.T) Purchases {
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"30.12.2024", "Маша/продукты - хлеб (50), кафе (300)"},
+		{"03.01.2025", "продукты - сыр (150), +зарплата (1000)"},
+		{"10.02.2025", "продукты - хлеб (60), кафе (440)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+	return purchases
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"month", "category"}, splitList(" Month, ,category "))
+	assert.Nil(t, splitList(""))
+}
+
+func TestReportDimensions(t *testing.T) {
+	saveGlobals(t)
+	p := reportTestPurchases(t)[0]
+	expected := map[string]string{
+		"day":      "2024-12-30",
+		"week":     "2025-W01",
+		"month":    "2024-12",
+		"quarter":  "2024-Q4",
+		"year":     "2024",
+		"person":   "маша",
+		"category": "продукты",
+		"name":     "хлеб",
+	}
+	for d, key := range expected {
+		assert.Equal(t, key, reportDimensions[d](p), d)
+	}
+}
+
+func TestReport(t *testing.T) {
+	saveGlobals(t)
+	pp := reportTestPurchases(t)
+
+	groups, err := pp.report([]string{"category"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*reportGroup{
+		{keys: []string{"кафе"}, total: 740, count: 2},
+		{keys: []string{"продукты"}, total: 260, count: 3},
+	}, groups, "income is skipped")
+
+	groups, err = pp.report([]string{"quarter", "category"})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 4)
+	assert.Equal(t, []string{"2024-Q4", "кафе"}, groups[0].keys)
+
+	groups, err = pp.report(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*reportGroup{{total: 1000, count: 5}}, groups)
+
+	_, err = pp.report([]string{"shop"})
+	assert.EqualError(t, err, "unknown report dimension: shop")
+}
+
+func TestReportTable(t *testing.T) {
+	groups := []*reportGroup{
+		{keys: []string{"кафе"}, total: 740, count: 2},
+		{keys: []string{"продукты"}, total: 260, count: 3},
+	}
+	tbl, err := reportTable(groups, []string{"category"}, []string{"total", "count", "avg", "share"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Category", "Total", "Count", "Avg", "Share"}, tbl.header())
+	assert.Equal(t, [][]string{
+		{"кафе", "740", "2", "370.00", "74.0"},
+		{"продукты", "260", "3", "86.67", "26.0"},
+	}, tbl.rows)
+
+	_, err = reportTable(groups, []string{"category"}, []string{"median"})
+	assert.EqualError(t, err, "unknown report metric: median")
+}
+
+func TestReportCommand(t *testing.T) {
+	saveGlobals(t)
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "default",
+			expected: `Month    Category  Total  Count
+2024-12  кафе      300    1
+2024-12  продукты  50     1
+2025-01  продукты  150    1
+2025-02  кафе      440    1
+2025-02  продукты  60     1
+`,
+		},
+		{
+			name: "sort by total",
+			args: []string{"-by", "category", "-metrics", "total,share", "-sort", "total"},
+			expected: `Category  Total  Share
+кафе      740    74.0
+продукты  260    26.0
+`,
+		},
+		{
+			name: "filter",
+			args: []string{"-by", "person", "-from", "01.01.2025", "-output", "csv"},
+			expected: `Person,Total,Count
+общие,650,3
+`,
+		},
+		{
+			name: "json",
+			args: []string{"-by", "year", "-metrics", "total,avg", "-output", "json"},
+			expected: `[
+  {"Year": "2024", "Total": 350, "Avg": 175.00},
+  {"Year": "2025", "Total": 650, "Avg": 216.67}
+]
+`,
+		},
+		{
+			name: "markdown",
+			args: []string{"-by", "year", "-output", "md"},
+			expected: `| Year | Total | Count |
+| --- | ---: | ---: |
+| 2024 | 350 | 2 |
+| 2025 | 650 | 3 |
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, reportCommand(&buf, reportTestPurchases(t), tt.args))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+
+	for _, args := range [][]string{
+		{"-by", "shop"},
+		{"-metrics", "median"},
+		{"-sort", "name"},
+		{"-output", "xml"},
+	} {
+		assert.Error(t, reportCommand(&bytes.Buffer{}, reportTestPurchases(t), args), args)
+	}
+}
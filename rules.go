@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Auto-categorisation rule from config. Patterns are globs like "кофе*",
+// or regexps when enclosed in slashes like "/^(кофе|капучино)$/".
+// Dates are in YYYY-MM-DD format, prices are in roubles.
+type Rule struct {
+	Name  string `yaml:"name"`
+	Match struct {
+		Name     string `yaml:"name"`
+		Category string `yaml:"category"`
+		Person   string `yaml:"person"`
+		MinPrice *int   `yaml:"min_price"`
+		MaxPrice *int   `yaml:"max_price"`
+		From     string `yaml:"from"`
+		To       string `yaml:"to"`
+	} `yaml:"match"`
+	Set struct {
+		Name     string `yaml:"name"`
+		Category string `yaml:"category"`
+		Person   string `yaml:"person"`
+	} `yaml:"set"`
+}
+
+// Rules applied by newCommodity, first matching rule wins
+var rules []*rule
+
+type pattern struct {
+	re   *regexp.Regexp
+	glob string
+}
+
+func compilePattern(s string) (*pattern, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if len(s) > 1 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, err
+		}
+		return &pattern{re: re}, nil
+	}
+	if _, err := path.Match(s, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %s: %w", s, err)
+	}
+	return &pattern{glob: strings.ToLower(s)}, nil
+}
+
+// Nil pattern matches everything
+func (p *pattern) match(s string) bool {
+	if p == nil {
+		return true
+	}
+	if p.re != nil {
+		return p.re.MatchString(s)
+	}
+	matched, _ := path.Match(p.glob, s)
+	return matched
+}
+
+type rule struct {
+	*Rule
+	name, category, person *pattern
+	from, to               time.Time
+}
+
+func compileRules(rr []Rule) ([]*rule, error) {
+	var compiled []*rule
+	for i := range rr {
+		r := &rule{Rule: &rr[i]}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		var err error
+		if r.name, err = compilePattern(r.Match.Name); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+		if r.category, err = compilePattern(r.Match.Category); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+		if r.person, err = compilePattern(r.Match.Person); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+		if r.Match.From != "" {
+			if r.from, err = time.Parse(time.DateOnly, r.Match.From); err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Name, err)
+			}
+		}
+		if r.Match.To != "" {
+			if r.to, err = time.Parse(time.DateOnly, r.Match.To); err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Name, err)
+			}
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+func (r *rule) match(c *Commodity, date time.Time) bool {
+	if !r.name.match(c.name) || !r.category.match(c.category) || !r.person.match(c.person) {
+		return false
+	}
+	if r.Match.MinPrice != nil && c.price < *r.Match.MinPrice {
+		return false
+	}
+	if r.Match.MaxPrice != nil && c.price > *r.Match.MaxPrice {
+		return false
+	}
+	if !r.from.IsZero() && date.Before(r.from) {
+		return false
+	}
+	if !r.to.IsZero() && date.After(r.to) {
+		return false
+	}
+	return true
+}
+
+// Rewrite commodity with the first matching rule and remember its name.
+// Shared commodity is matched by persons joined with "+", and setting
+// a person makes it not shared anymore.
+func applyRules(c *Commodity, date time.Time) {
+	for _, r := range rules {
+		if !r.match(c, date) {
+			continue
+		}
+		if r.Set.Name != "" {
+			c.name = strings.ToLower(r.Set.Name)
+		}
+		if r.Set.Category != "" {
+			c.setCategory(strings.ToLower(r.Set.Category))
+		}
+		if r.Set.Person != "" {
+			c.person = strings.ToLower(r.Set.Person)
+			c.participants = nil
+		}
+		c.rule = r.Name
+		return
+	}
+}
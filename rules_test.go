@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRules = `
+rules:
+  - name: кафе
+    match:
+      name: /^(кофе|капучино)$/
+    set:
+      category: Кафе
+  - name: такси
+    match:
+      category: разное
+      name: такси*
+    set:
+      category: транспорт
+  - name: крупная техника
+    match:
+      category: техника
+      min_price: 10000
+      from: "2024-01-01"
+      to: "2024-12-31"
+    set:
+      name: крупная техника
+      person: общие
+  - match:
+      person: петя
+    set:
+      person: пётр
+`
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		input    string
+		expected bool
+	}{
+		{"empty pattern matches everything", "", "что угодно", true},
+		{"exact glob", "кофе", "кофе", true},
+		{"exact glob mismatch", "кофе", "кофейник", false},
+		{"star glob", "коф*", "кофейник", true},
+		{"glob is case insensitive", "Кофе", "кофе", true},
+		{"regexp", "/^(кофе|капучино)$/", "капучино", true},
+		{"regexp mismatch", "/^(кофе|капучино)$/", "латте", false},
+		{"regexp partial", "/такси/", "яндекс такси", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p.match(tt.input))
+		})
+	}
+
+	_, err := compilePattern("/(/")
+	assert.Error(t, err)
+	_, err = compilePattern("[")
+	assert.Error(t, err)
+}
+
+func TestApplyRules(t *testing.T) {
+	saveGlobals(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testRules), 0644))
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+	rules, err = compileRules(cfg.Rules)
+	assert.NoError(t, err)
+
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input            string
+		date             time.Time
+		expectedPerson   string
+		expectedCategory string
+		expectedName     string
+		expectedRule     string
+	}{
+		{"кофе (200)", date, "общие", "кафе", "кофе", "кафе"},
+		{"Маша/разное - капучино (250)", date, "маша", "кафе", "капучино", "кафе"},
+		{"Общие/разное - такси (500)", date, "общие", "транспорт", "такси", "такси"},
+		{"Общие/разное - такси до дома (500)", date, "общие", "транспорт", "такси до дома", "такси"},
+		{"Продукты - такси (500)", date, "общие", "продукты", "такси", ""},
+		{"Маша/техника - холодильник (50000)", date, "общие", "техника", "крупная техника", "крупная техника"},
+		{"Маша/техника - чайник (3000)", date, "маша", "техника", "чайник", ""},
+		{"Маша/техника - холодильник (50000)", date.AddDate(1, 0, 0), "маша", "техника", "холодильник", ""},
+		{"Петя/игры (1000)", date, "пётр", "игры", "игры", "rule 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := newCommodity(tt.input, tt.date)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPerson, c.person)
+			assert.Equal(t, tt.expectedCategory, c.category)
+			assert.Equal(t, tt.expectedName, c.name)
+			assert.Equal(t, tt.expectedRule, c.rule)
+		})
+	}
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	r := Rule{Name: "bad date"}
+	r.Match.From = "01.01.2024"
+	_, err := compileRules([]Rule{r})
+	assert.ErrorContains(t, err, "bad date")
+
+	r = Rule{}
+	r.Match.Name = "/(/"
+	_, err = compileRules([]Rule{r})
+	assert.ErrorContains(t, err, "rule 1")
+}
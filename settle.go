@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+type transfer struct {
+	from, to string
+	amount   int
+}
+
+// Net balance per person over purchases with a payer: the payer is owed
+// the price and the person the purchase is for owes it
+func (pp Purchases) balances() map[string]int {
+	balances := map[string]int{}
+	for _, p := range pp {
+		c := p.commodity
+		if c.payer == "" || !c.isExpense() {
+			continue
+		}
+		balances[c.payer] += c.price
+		balances[c.person] -= c.price
+	}
+	return balances
+}
+
+// Settle balances with transfers from debtors to creditors. The largest
+// creditor is paid first, by a debtor owing exactly the same amount if any,
+// by the largest debtor otherwise. It takes at most n-1 transfers for n persons.
+func settle(balances map[string]int) []transfer {
+	type entry struct {
+		person string
+		amount int
+	}
+	var creditors, debtors []*entry
+	for _, person := range sortedKeys(balances) {
+		switch b := balances[person]; {
+		case b > 0:
+			creditors = append(creditors, &entry{person, b})
+		case b < 0:
+			debtors = append(debtors, &entry{person, -b})
+		}
+	}
+	largest := func(entries []*entry) {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].amount > entries[j].amount
+		})
+	}
+
+	var transfers []transfer
+	for len(creditors) > 0 && len(debtors) > 0 {
+		largest(creditors)
+		largest(debtors)
+		creditor, d := creditors[0], 0
+		for i, debtor := range debtors {
+			if debtor.amount == creditor.amount {
+				d = i
+				break
+			}
+		}
+		debtor := debtors[d]
+		amount := min(creditor.amount, debtor.amount)
+		transfers = append(transfers, transfer{debtor.person, creditor.person, amount})
+		creditor.amount -= amount
+		debtor.amount -= amount
+		if creditor.amount == 0 {
+			creditors = creditors[1:]
+		}
+		if debtor.amount == 0 {
+			debtors = append(debtors[:d], debtors[d+1:]...)
+		}
+	}
+	return transfers
+}
+
+// Print net balances per person and transfers to settle up
+func settleCommand(w io.Writer, pp Purchases, args []string) error {
+	var filter purchaseFilter
+	fs := newFlagSet("settle")
+	filter.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pp, err := filter.apply(pp)
+	if err != nil {
+		return err
+	}
+
+	balances := pp.balances()
+	t := &table{columns: []column{{"Person", kindString}, {"Balance", kindDecimal}}}
+	for _, person := range sortedKeys(balances) {
+		t.rows = append(t.rows, []string{person, fmt.Sprintf("%+d", balances[person])})
+	}
+	if err := t.writeText(w); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	t = &table{columns: []column{{"From", kindString}, {"To", kindString}, {"Amount", kindDecimal}}}
+	for _, tr := range settle(balances) {
+		t.rows = append(t.rows, []string{tr.from, tr.to, strconv.Itoa(tr.amount)})
+	}
+	return t.writeText(w)
+}
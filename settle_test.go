@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutPayer(t *testing.T) {
+	saveGlobals(t)
+	personAliases["мария"] = "маша"
+
+	s, payer := cutPayer("кафе (900) @Мария")
+	assert.Equal(t, "кафе (900)", s)
+	assert.Equal(t, "маша", payer)
+
+	s, payer = cutPayer("@петя Маша+Петя/кафе (900)")
+	assert.Equal(t, " Маша+Петя/кафе (900)", s)
+	assert.Equal(t, "петя", payer)
+
+	s, payer = cutPayer("Special@item#test (100)")
+	assert.Equal(t, "Special@item#test (100)", s, "@ inside a word is not a payer")
+	assert.Equal(t, "", payer)
+
+	c, err := newCommodity(" Маша+Петя/кафе (900) @маша", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "маша", c.payer)
+	for _, part := range c.split() {
+		assert.Equal(t, "маша", part.payer)
+	}
+}
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances map[string]int
+		expected []transfer
+	}{
+		{
+			name:     "nothing to settle",
+			balances: map[string]int{"маша": 0},
+		},
+		{
+			name:     "one debtor",
+			balances: map[string]int{"маша": 600, "петя": -600},
+			expected: []transfer{{"петя", "маша", 600}},
+		},
+		{
+			name:     "largest creditor first",
+			balances: map[string]int{"маша": 700, "петя": 300, "вася": -600, "коля": -400},
+			expected: []transfer{{"вася", "маша", 600}, {"коля", "петя", 300}, {"коля", "маша", 100}},
+		},
+		{
+			name:     "exact match saves a transfer",
+			balances: map[string]int{"маша": 400, "петя": 300, "коля": 300, "вася": -600, "оля": -400},
+			expected: []transfer{{"оля", "маша", 400}, {"вася", "коля", 300}, {"вася", "петя", 300}},
+		},
+		{
+			name:     "exact match is preferred",
+			balances: map[string]int{"маша": 500, "петя": 200, "вася": -500, "коля": -100, "оля": -100},
+			expected: []transfer{{"вася", "маша", 500}, {"коля", "петя", 100}, {"оля", "петя", 100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers := settle(tt.balances)
+			assert.Equal(t, tt.expected, transfers)
+			assert.LessOrEqual(t, len(transfers), max(len(tt.balances)-1, 0))
+		})
+	}
+}
+
+func TestSettleCommand(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.03.2024", "Маша+Петя+Вася/кафе (900) @маша, Петя/такси (300) @вася, хлеб (50)"},
+		{"05.03.2024", "Вася/кино (600) @петя"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	var buf bytes.Buffer
+	assert.NoError(t, settleCommand(&buf, purchases, nil))
+	expected := `Person  Balance
+вася    -600
+маша    +600
+петя    +0
+
+From  To    Amount
+вася  маша  600
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, settleCommand(&buf, purchases, []string{"-to", "01.03.2024"}))
+	assert.Contains(t, buf.String(), "петя  маша  600\n")
+
+	assert.Error(t, settleCommand(&buf, purchases, []string{"-from", "2024-03-01"}))
+}
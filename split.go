@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Participant of a shared purchase with a weight of the share
+type participant struct {
+	person string
+	weight int
+}
+
+// Parse "маша+петя" or weighted "маша:2+петя:1" person list,
+// returns nil for a single person, who can't have a weight
+func parseParticipants(s string) ([]participant, error) {
+	if !strings.Contains(s, "+") {
+		if strings.Contains(s, ":") {
+			return nil, fmt.Errorf("share weight without other participants: %s", s)
+		}
+		return nil, nil
+	}
+	var participants []participant
+	for _, item := range strings.Split(s, "+") {
+		person, strWeight, weighted := strings.Cut(item, ":")
+		person = strings.TrimSpace(person)
+		if person == "" {
+			return nil, fmt.Errorf("invalid participants format: %s", s)
+		}
+		weight := 1
+		if weighted {
+			var err error
+			if weight, err = strconv.Atoi(strings.TrimSpace(strWeight)); err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid share weight: %s", item)
+			}
+		}
+		participants = append(participants, participant{resolvePerson(person), weight})
+	}
+	return participants, nil
+}
+
+// Split total into shares proportional to weights. Shares are rounded down
+// and the remainder goes one by one to the largest fractional parts,
+// earlier participants win ties, so shares always add up to total.
+func splitShares(total int, weights []int) []int {
+	sign := 1
+	if total < 0 {
+		sign, total = -1, -total
+	}
+	var sum int
+	for _, w := range weights {
+		sum += w
+	}
+
+	shares := make([]int, len(weights))
+	remainders := make([]int, len(weights))
+	order := make([]int, len(weights))
+	left := total
+	for i, w := range weights {
+		shares[i] = total * w / sum
+		remainders[i] = total * w % sum
+		order[i] = i
+		left -= shares[i]
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; i < left; i++ {
+		shares[order[i]]++
+	}
+
+	for i := range shares {
+		shares[i] *= sign
+	}
+	return shares
+}
+
+// Return a commodity per participant with its share of price, amount and quantity,
+// or commodity itself if it's not shared
+func (c *Commodity) split() []*Commodity {
+	if len(c.participants) == 0 {
+		return []*Commodity{c}
+	}
+	var weights []int
+	var sum int
+	for _, p := range c.participants {
+		weights = append(weights, p.weight)
+		sum += p.weight
+	}
+	var result []*Commodity
+	for i, share := range splitShares(c.price, weights) {
+		part := *c
+		part.tags = slices.Clone(c.tags)
+		part.person = c.participants[i].person
+		part.price = share
+		part.amount = c.amount * float64(weights[i]) / float64(sum)
+		part.quantity = c.quantity * float64(weights[i]) / float64(sum)
+		part.participants = nil
+		result = append(result, &part)
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParticipants(t *testing.T) {
+	saveGlobals(t)
+	personAliases["мария"] = "маша"
+
+	participants, err := parseParticipants("маша")
+	assert.NoError(t, err)
+	assert.Nil(t, participants)
+
+	participants, err = parseParticipants("мария+петя")
+	assert.NoError(t, err)
+	assert.Equal(t, []participant{{"маша", 1}, {"петя", 1}}, participants)
+
+	participants, err = parseParticipants("маша:2 + петя:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []participant{{"маша", 2}, {"петя", 1}}, participants)
+
+	for _, s := range []string{"маша+", "маша:0+петя", "маша:x+петя", "маша:-1+петя", "маша:2"} {
+		_, err = parseParticipants(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSplitShares(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		weights  []int
+		expected []int
+	}{
+		{"equal shares", 1000, []int{1, 1}, []int{500, 500}},
+		{"remainder goes to first on tie", 1000, []int{1, 1, 1}, []int{334, 333, 333}},
+		{"weighted", 1000, []int{2, 1}, []int{667, 333}},
+		{"largest remainder wins", 10, []int{1, 2, 4}, []int{1, 3, 6}},
+		{"zero total", 0, []int{1, 1}, []int{0, 0}},
+		{"negative total", -1000, []int{1, 1, 1}, []int{-334, -333, -333}},
+		{"single share", 999, []int{5}, []int{999}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := splitShares(tt.total, tt.weights)
+			assert.Equal(t, tt.expected, shares)
+			var sum int
+			for _, s := range shares {
+				sum += s
+			}
+			assert.Equal(t, tt.total, sum, "shares must add up to total")
+		})
+	}
+}
+
+func TestSplitPurchases(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	personAliases["мария"] = "маша"
+
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.03.2024", "Маша+Петя/кафе (1000), Мария:2+Петя:1/кино - билеты (1000), Маша/хлеб (50)"},
+		{"02.03.2024", "Маша+/кафе (100)"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Len(t, errors, 1)
+	assert.Equal(t, 3, errors[0].row)
+
+	var actual [][]string
+	for _, p := range purchases {
+		actual = append(actual, p.toArray()[1:5])
+	}
+	assert.Equal(t, [][]string{
+		{"маша", "кафе", "кафе", "500"},
+		{"петя", "кафе", "кафе", "500"},
+		{"маша", "кино", "билеты", "667"},
+		{"петя", "кино", "билеты", "333"},
+		{"маша", "хлеб", "хлеб", "50"},
+	}, actual)
+}
+
+func TestSplitAmount(t *testing.T) {
+	c, err := newCommodity("Маша:3+Петя/кафе (€20=2000)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "маша+петя", c.person)
+
+	parts := c.split()
+	assert.Len(t, parts, 2)
+	assert.Equal(t, 1500, parts[0].price)
+	assert.Equal(t, float64(15), parts[0].amount)
+	assert.Equal(t, "EUR", parts[1].currency)
+	assert.Equal(t, float64(5), parts[1].amount)
+	assert.Nil(t, parts[1].participants)
+}
+
+func TestSplitTags(t *testing.T) {
+	c := &Commodity{price: 1000, tags: make([]string, 1, 4), participants: []participant{{"маша", 1}, {"петя", 1}}}
+	c.tags[0] = "отпуск"
+	parts := c.split()
+	parts[0].addTags("кафе")
+	parts[1].addTags("музей")
+	assert.Equal(t, []string{"отпуск", "кафе"}, parts[0].tags)
+	assert.Equal(t, []string{"отпуск", "музей"}, parts[1].tags, "parts don't share tags")
+	assert.Equal(t, []string{"отпуск"}, c.tags)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dimension table with surrogate keys assigned in order of first appearance
+type dimension struct {
+	table
+	keys map[string]int
+}
+
+func newDimension(name string, columns ...column) *dimension {
+	columns = append([]column{{name + "_key", kindInt}}, columns...)
+	return &dimension{
+		table: table{name: "dim_" + name, columns: columns},
+		keys:  map[string]int{},
+	}
+}
+
+// Return surrogate key for id, adding a row with values if it's new
+func (d *dimension) key(id string, values ...string) int {
+	if k, ok := d.keys[id]; ok {
+		return k
+	}
+	k := len(d.keys) + 1
+	d.keys[id] = k
+	d.rows = append(d.rows, append([]string{strconv.Itoa(k)}, values...))
+	return k
+}
+
+// Symbol of the currency code, the first one in sort order if there are
+// several symbols of the code, so that output doesn't change between runs
+func currencySymbol(code string) string {
+	for _, symbol := range sortedKeys(currencySymbols) {
+		if currencySymbols[symbol] == code {
+			return symbol
+		}
+	}
+	if code == DEFAULT_CURRENCY {
+		return ROUBLE_SYMBOL
+	}
+	return ""
+}
+
+func dateKey(d time.Time) int {
+	return d.Year()*10000 + int(d.Month())*100 + d.Day()
+}
+
+// Calendar dimension with a row for every day between first and last purchase
+func (pp Purchases) calendar() *table {
+	t := &table{
+		name: "dim_calendar",
+		columns: []column{
+			{"date_key", kindInt},
+			{"date", kindDate},
+			{"year", kindInt},
+			{"quarter", kindInt},
+			{"month", kindInt},
+			{"day", kindInt},
+			{"weekday", kindInt},
+			{"week", kindInt},
+		},
+	}
+	if len(pp) == 0 {
+		return t
+	}
+	from, to := pp[0].date, pp[0].date
+	for _, p := range pp {
+		if p.date.Before(from) {
+			from = p.date
+		}
+		if p.date.After(to) {
+			to = p.date
+		}
+	}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		_, week := d.ISOWeek()
+		weekday := int(d.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		t.rows = append(t.rows, []string{
+			strconv.Itoa(dateKey(d)),
+			d.Format(df),
+			strconv.Itoa(d.Year()),
+			strconv.Itoa((int(d.Month())-1)/3 + 1),
+			strconv.Itoa(int(d.Month())),
+			strconv.Itoa(d.Day()),
+			strconv.Itoa(weekday),
+			strconv.Itoa(week),
+		})
+	}
+	return t
+}
+
+// Split purchases into fact table with surrogate keys and dimension tables
+func (pp Purchases) toStar() []*table {
+	persons := newDimension("person", column{"person", kindDict})
+	categories := newDimension("category",
+		column{"category", kindDict}, column{"subcategory", kindDict}, column{"original_category", kindDict})
+	items := newDimension("item", column{"name", kindString})
+	currencies := newDimension("currency", column{"code", kindDict}, column{"symbol", kindDict})
+	accounts := newDimension("account", column{"account", kindDict})
+	tags := newDimension("tag", column{"tag", kindDict})
+	// Purchase may have many tags, so they are linked via bridge table
+	bridge := &table{
+		name:    "bridge_purchase_tag",
+		columns: []column{{"purchase_key", kindInt}, {"tag_key", kindInt}},
+	}
+
+	fact := &table{
+		name: "fact_purchases",
+		columns: []column{
+			{"purchase_key", kindInt},
+			{"date_key", kindInt},
+			{"person_key", kindInt},
+			{"category_key", kindInt},
+			{"item_key", kindInt},
+			{"currency_key", kindInt},
+			{"account_key", kindInt},
+			{"amount", kindDecimal},
+			{"price", kindDecimal},
+			{"type", kindDict},
+			{"note", kindString},
+		},
+	}
+	for i, p := range pp {
+		c := p.commodity
+		// Rules may put items of the same original category into different
+		// categories, so the whole tuple identifies a category row
+		categoryKey := categories.key(strings.Join([]string{c.category, c.subcategory, c.origCategory}, "\x00"),
+			c.category, c.subcategory, c.origCategory)
+		// Purchases without account have no account key
+		var accountKey string
+		if c.account != "" {
+			accountKey = strconv.Itoa(accounts.key(c.account, c.account))
+		}
+		fact.rows = append(fact.rows, []string{
+			strconv.Itoa(i + 1),
+			strconv.Itoa(dateKey(p.date)),
+			strconv.Itoa(persons.key(c.person, c.person)),
+			strconv.Itoa(categoryKey),
+			strconv.Itoa(items.key(c.name, c.name)),
+			strconv.Itoa(currencies.key(c.currency, c.currency, currencySymbol(c.currency))),
+			accountKey,
+			strconv.FormatFloat(c.amount, 'f', -1, 64),
+			strconv.Itoa(c.price),
+			string(c.entryType()),
+			c.note,
+		})
+		for _, tag := range c.tags {
+			bridge.rows = append(bridge.rows, []string{strconv.Itoa(i + 1), strconv.Itoa(tags.key(tag, tag))})
+		}
+	}
+
+	return []*table{fact, &persons.table, &categories.table, &items.table, &currencies.table, &accounts.table, &tags.table, bridge, pp.calendar()}
+}
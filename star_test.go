@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToStar(t *testing.T) {
+	df = "02.01.2006"
+
+	purchases := Purchases{
+		&Purchase{
+			date: time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{
+				person: "общие", category: "транспорт", subcategory: "метро", origCategory: "метро", name: "метро",
+				price: 60, currency: "RUB", amount: 60,
+			},
+		},
+		&Purchase{
+			date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{
+				person: "маша", category: "кафе", origCategory: "кафе", name: "кофе",
+				price: 900, currency: "EUR", amount: 9.5, account: "карта",
+				tags: []string{"отпуск", "франция"}, note: "с видом",
+			},
+		},
+		&Purchase{
+			date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{
+				person: "общие", category: "транспорт", subcategory: "автобус", origCategory: "автобус", name: "автобус",
+				price: 50, currency: "RUB", amount: 50,
+				tags: []string{"отпуск"},
+			},
+		},
+	}
+
+	tables := map[string]*table{}
+	for _, tbl := range purchases.toStar() {
+		tables[tbl.name] = tbl
+	}
+
+	assert.Equal(t, [][]string{
+		{"1", "20231230", "1", "1", "1", "1", "", "60", "60", "expense", ""},
+		{"2", "20240102", "2", "2", "2", "2", "1", "9.5", "900", "expense", "с видом"},
+		{"3", "20240102", "1", "3", "3", "1", "", "50", "50", "expense", ""},
+	}, tables["fact_purchases"].rows)
+
+	assert.Equal(t, [][]string{{"1", "общие"}, {"2", "маша"}}, tables["dim_person"].rows)
+	assert.Equal(t, [][]string{
+		{"1", "транспорт", "метро", "метро"},
+		{"2", "кафе", "", "кафе"},
+		{"3", "транспорт", "автобус", "автобус"},
+	}, tables["dim_category"].rows)
+	assert.Equal(t, [][]string{{"1", "метро"}, {"2", "кофе"}, {"3", "автобус"}}, tables["dim_item"].rows)
+	assert.Equal(t, [][]string{{"1", "RUB", "₽"}, {"2", "EUR", "€"}}, tables["dim_currency"].rows)
+	assert.Equal(t, [][]string{{"1", "карта"}}, tables["dim_account"].rows)
+	assert.Equal(t, [][]string{{"1", "отпуск"}, {"2", "франция"}}, tables["dim_tag"].rows)
+	assert.Equal(t, [][]string{{"2", "1"}, {"2", "2"}, {"3", "1"}}, tables["bridge_purchase_tag"].rows)
+
+	calendar := tables["dim_calendar"]
+	assert.Len(t, calendar.rows, 4, "calendar should cover 30.12.2023 - 02.01.2024")
+	assert.Equal(t, []string{"20231230", "30.12.2023", "2023", "4", "12", "30", "6", "52"}, calendar.rows[0])
+	assert.Equal(t, []string{"20240101", "01.01.2024", "2024", "1", "1", "1", "1", "1"}, calendar.rows[2])
+}
+
+func TestCalendarEmpty(t *testing.T) {
+	assert.Empty(t, Purchases{}.calendar().rows)
+}
+
+func TestCurrencySymbol(t *testing.T) {
+	saveGlobals(t)
+	currencySymbols["US$"] = "USD"
+	currencySymbols["дол"] = "USD"
+	for range 10 {
+		assert.Equal(t, "$", currencySymbol("USD"), "the first symbol in sort order")
+	}
+	assert.Equal(t, "₽", currencySymbol("RUB"))
+	assert.Equal(t, "", currencySymbol("GEL"))
+}
+
+func TestToStarRules(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	var taxi Rule
+	taxi.Match.Name = "такси"
+	taxi.Set.Category = "транспорт"
+	var err error
+	rules, err = compileRules([]Rule{taxi})
+	assert.NoError(t, err)
+
+	purchases, errors := getPurchases([][]string{
+		{"Date", "Items"},
+		{"10.01.2024", "разное - такси (500), разное - ручка (50)"},
+	})
+	assert.Empty(t, errors)
+
+	tables := map[string]*table{}
+	for _, tbl := range purchases.toStar() {
+		tables[tbl.name] = tbl
+	}
+	assert.Equal(t, "1", tables["fact_purchases"].rows[0][3])
+	assert.Equal(t, "2", tables["fact_purchases"].rows[1][3], "rule doesn't move other items of the category")
+	assert.Equal(t, [][]string{
+		{"1", "транспорт", "", "разное"},
+		{"2", "разное", "", "разное"},
+	}, tables["dim_category"].rows)
+}
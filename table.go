@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindDict              // low-cardinality string like person or category
+	kindInt
+	kindDate    // formatted with df
+	kindDecimal // roubles or other currency amount
+)
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+// Named table of string values, the way finparser writes everything
+// besides the flat purchases CSV
+type table struct {
+	name    string
+	columns []column
+	rows    [][]string
+}
+
+func (t *table) header() []string {
+	var h []string
+	for _, c := range t.columns {
+		h = append(h, c.name)
+	}
+	return h
+}
+
+func (t *table) writeCsv(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.header()); err != nil {
+		return err
+	}
+	return cw.WriteAll(t.rows)
+}
+
+// Write every table as <name>.<ext> to directory, creating it if needed
+func writeTables(dir string, tables []*table, ext string, write func(*table, io.Writer) error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		f, err := os.Create(filepath.Join(dir, t.name+"."+ext))
+		if err != nil {
+			return err
+		}
+		if err := write(t, f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write table aligned with spaces for reading in terminal,
+// padding of empty trailing cells is trimmed
+func (t *table) writeText(w io.Writer) error {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header(), "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write table as JSON array of objects keyed by column names in column order,
+// numeric cells are numbers and empty ones are nulls
+func (t *table) writeJSON(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for r, row := range t.rows {
+		if r > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for i, c := range t.columns {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			key, err := json.Marshal(c.name)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteString(": ")
+			value, err := jsonValue(c.kind, row[i])
+			if err != nil {
+				return err
+			}
+			buf.Write(value)
+		}
+		buf.WriteString("}")
+	}
+	if len(t.rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func jsonValue(kind columnKind, s string) ([]byte, error) {
+	if kind == kindInt || kind == kindDecimal {
+		if s == "" {
+			return []byte("null"), nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil && json.Valid([]byte(s)) {
+			return []byte(s), nil
+		}
+	}
+	return json.Marshal(s)
+}
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ")
+
+// Write table as GitHub flavoured Markdown, numeric columns are right-aligned
+func (t *table) writeMarkdown(w io.Writer) error {
+	var header, align []string
+	for _, c := range t.columns {
+		header = append(header, markdownEscaper.Replace(c.name))
+		if c.kind == kindInt || c.kind == kindDecimal {
+			align = append(align, "---:")
+		} else {
+			align = append(align, "---")
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "| %s |\n", strings.Join(header, " | "))
+	fmt.Fprintf(&buf, "| %s |\n", strings.Join(align, " | "))
+	for _, row := range t.rows {
+		var cells []string
+		for _, cell := range row {
+			cells = append(cells, markdownEscaper.Replace(cell))
+		}
+		fmt.Fprintf(&buf, "| %s |\n", strings.Join(cells, " | "))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Write table as text, csv, json or md
+func (t *table) writeFormat(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		return t.writeText(w)
+	case "csv":
+		return t.writeCsv(w)
+	case "json":
+		return t.writeJSON(w)
+	case "md":
+		return t.writeMarkdown(w)
+	}
+	return fmt.Errorf("unknown output format: %s", format)
+}
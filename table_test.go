@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTables(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "star")
+	tables := []*table{
+		{
+			name:    "dim_person",
+			columns: []column{{"person_key", kindInt}, {"person", kindString}},
+			rows:    [][]string{{"1", "общие"}, {"2", "маша, петя"}},
+		},
+		{
+			name:    "empty",
+			columns: []column{{"id", kindInt}},
+		},
+	}
+
+	assert.NoError(t, writeTables(dir, tables, "csv", (*table).writeCsv))
+
+	data, err := os.ReadFile(filepath.Join(dir, "dim_person.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "person_key,person\n1,общие\n2,\"маша, петя\"\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "empty.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "id\n", string(data))
+}
+
+func TestWriteJSON(t *testing.T) {
+	tbl := &table{
+		columns: []column{{"Name", kindString}, {"Total", kindInt}, {"Share", kindDecimal}},
+		rows:    [][]string{{"хлеб \"бородинский\"", "50", "12.5"}, {"сыр", "120", ""}},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, tbl.writeJSON(&buf))
+	expected := `[
+  {"Name": "хлеб \"бородинский\"", "Total": 50, "Share": 12.5},
+  {"Name": "сыр", "Total": 120, "Share": null}
+]
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, (&table{columns: tbl.columns}).writeJSON(&buf))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	tbl := &table{
+		columns: []column{{"Name", kindString}, {"Total", kindInt}},
+		rows:    [][]string{{"хлеб | батон", "50"}},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, tbl.writeMarkdown(&buf))
+	expected := `| Name | Total |
+| --- | ---: |
+| хлеб \| батон | 50 |
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Separator of tags in the Tags output column
+const TAGS_SEPARATOR = ";"
+
+// Cut trailing "// note" off the item, the note may contain anything but commas.
+// The note follows the price, so that "//" in the price is an error.
+func cutNote(s string) (string, string, error) {
+	loc := reNote.FindStringIndex(s)
+	if loc == nil {
+		return s, "", nil
+	}
+	if end := strings.Index(s, ")"); end >= 0 && loc[0] < end {
+		return "", "", fmt.Errorf("note must follow the price: %s", s)
+	}
+	return s[:loc[0]], strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(s[loc[0]:]), "/")), nil
+}
+
+// Cut "#tag" markers off the item, returns lowercased tags in order of
+// appearance. Tags must start a word and with a letter, so "item#1" and
+// "такси #2" are kept as is.
+func cutTags(s string) (string, []string) {
+	var tags []string
+	s = reTag.ReplaceAllStringFunc(s, func(m string) string {
+		tags = append(tags, strings.ToLower(reTag.FindStringSubmatch(m)[2]))
+		return ""
+	})
+	return s, tags
+}
+
+// Add tags missing in the commodity, keeping the order
+func (c *Commodity) addTags(tags ...string) {
+	for _, tag := range tags {
+		if !slices.Contains(c.tags, tag) {
+			c.tags = append(c.tags, tag)
+		}
+	}
+}
+
+// Check if commodity has any of the tags, any commodity matches empty tag list
+func (c *Commodity) hasAnyTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if slices.Contains(c.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse comma separated tag list with or without "#"
+func parseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#")); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
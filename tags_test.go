@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutNote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		note     string
+	}{
+		{"кафе (2500)", "кафе (2500)", ""},
+		{"кафе (2500) // за двоих", "кафе (2500)", "за двоих"},
+		{"кафе (2500) //#не тег @не плательщик", "кафе (2500)", "#не тег @не плательщик"},
+		{"// только заметка", "", "только заметка"},
+		{"Маша//кафе (2500)", "Маша//кафе (2500)", ""},
+		{"кафе (2500) // за двоих (с Машей)", "кафе (2500)", "за двоих (с Машей)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			s, note, err := cutNote(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+			assert.Equal(t, tt.note, note)
+		})
+	}
+
+	_, _, err := cutNote("кафе // ужин (2500)")
+	assert.EqualError(t, err, "note must follow the price: кафе // ужин (2500)")
+	_, err = newCommodity("кафе // ужин (2500)", time.Time{})
+	assert.EqualError(t, err, "note must follow the price: кафе // ужин (2500)")
+}
+
+func TestCutTags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		tags     []string
+	}{
+		{"кафе (2500)", "кафе (2500)", nil},
+		{"кафе - ужин (2500) #отпуск #Турция", "кафе - ужин (2500)", []string{"отпуск", "турция"}},
+		{"#отпуск кафе (2500)", " кафе (2500)", []string{"отпуск"}},
+		{"Special@item#test (100)", "Special@item#test (100)", nil},
+		{"кафе (2500) #c#1", "кафе (2500)", []string{"c#1"}},
+		{"такси #2 (300)", "такси #2 (300)", nil},
+		{"кафе (2500) #2024 #отпуск", "кафе (2500) #2024", []string{"отпуск"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			s, tags := cutTags(tt.input)
+			assert.Equal(t, tt.expected, s)
+			assert.Equal(t, tt.tags, tags)
+		})
+	}
+}
+
+func TestNewCommodityTagsAndNote(t *testing.T) {
+	saveGlobals(t)
+
+	c, err := newCommodity("кафе - ужин (2500) #отпуск #турция #отпуск // за двоих", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "кафе", c.category)
+	assert.Equal(t, "ужин", c.name)
+	assert.Equal(t, 2500, c.price)
+	assert.Equal(t, []string{"отпуск", "турция"}, c.tags)
+	assert.Equal(t, "за двоих", c.note)
+
+	c, err = newCommodity("такси #2 (300)", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "такси #2", c.name, "numbers aren't tags")
+	assert.Empty(t, c.tags)
+
+	c, err = newCommodity("Маша+Петя/кафе (1000) @маша #отпуск", time.Time{})
+	assert.NoError(t, err)
+	for _, part := range c.split() {
+		assert.Equal(t, []string{"отпуск"}, part.tags)
+	}
+}
+
+func TestPurchaseFilterTags(t *testing.T) {
+	saveGlobals(t)
+	df = "02.01.2006"
+	records := [][]string{
+		{"Date", "Items"},
+		{"01.03.2024", "кафе (2500) #отпуск, хлеб (50), такси (700) #командировка"},
+		{"02.03.2024", "музей (1000) #Отпуск"},
+	}
+	purchases, errors := getPurchases(records)
+	assert.Empty(t, errors)
+
+	filter := purchaseFilter{tags: "#отпуск"}
+	filtered, err := filter.apply(purchases)
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 2)
+
+	filter = purchaseFilter{tags: "отпуск, командировка", from: "02.03.2024"}
+	filtered, err = filter.apply(purchases)
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "музей", filtered[0].commodity.name)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cashflowCommand(&buf, purchases, []string{"-tag", "отпуск"}))
+	assert.Contains(t, buf.String(), "2024-03  общие   0       3500")
+}
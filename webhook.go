@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook target from config, receives JSON payloads of subscribed events
+// with POST requests. Events are "errors", "budget" and "totals", all of
+// them by default.
+type Webhook struct {
+	URL     string            `yaml:"url"`
+	Events  []string          `yaml:"events"`
+	Headers map[string]string `yaml:"headers"`
+	// Retries of failed requests, 3 by default
+	Retries *int `yaml:"retries"`
+}
+
+var webhookEvents = []string{"errors", "budget", "totals"}
+
+// Webhooks notified after parsing
+var webhooks []Webhook
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Pause before the first retry, doubled for every next one
+var webhookBackoff = time.Second
+
+// Validate webhooks and fill in default events and retries
+func checkWebhooks(hooks []Webhook) ([]Webhook, error) {
+	var result []Webhook
+	for i, h := range hooks {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %d: invalid URL: %s", i+1, h.URL)
+		}
+		if len(h.Events) == 0 {
+			h.Events = webhookEvents
+		}
+		for _, event := range h.Events {
+			if !slices.Contains(webhookEvents, event) {
+				return nil, fmt.Errorf("webhook %d: unknown event: %s", i+1, event)
+			}
+		}
+		if h.Retries == nil {
+			retries := 3
+			h.Retries = &retries
+		}
+		if *h.Retries < 0 {
+			return nil, fmt.Errorf("webhook %d: retries must not be negative", i+1)
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+type webhookPayload struct {
+	Event   string          `json:"event"`
+	Time    time.Time       `json:"time"`
+	Errors  []webhookError  `json:"errors,omitempty"`
+	Budgets []webhookBudget `json:"budgets,omitempty"`
+	Totals  *webhookTotals  `json:"totals,omitempty"`
+}
+
+type webhookError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+type webhookBudget struct {
+	Name      string `json:"name"`
+	Period    string `json:"period"`
+	Limit     int    `json:"limit"`
+	Spent     int    `json:"spent"`
+	Projected int    `json:"projected"`
+}
+
+type webhookTotals struct {
+	Month      string         `json:"month"`
+	Income     int            `json:"income"`
+	Expenses   int            `json:"expenses"`
+	Categories map[string]int `json:"categories"`
+}
+
+// Payloads of events happened on the date: parse errors and exceeded
+// budgets if there are any, and month to date totals
+func (pp Purchases) webhookPayloads(errs []*ParseError, bb []Budget, date time.Time) []*webhookPayload {
+	var payloads []*webhookPayload
+	if len(errs) > 0 {
+		p := &webhookPayload{Event: "errors", Time: date}
+		for _, e := range errs {
+			p.Errors = append(p.Errors, webhookError{e.row, e.s})
+		}
+		payloads = append(payloads, p)
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	p := &webhookPayload{Event: "budget", Time: date}
+	for i := range bb {
+		s := pp.budgetStatus(&bb[i], day)
+		if s.status() != "exceeded" {
+			continue
+		}
+		p.Budgets = append(p.Budgets, webhookBudget{bb[i].Name, s.periodName(), s.limit, s.spent, s.projected})
+	}
+	if len(p.Budgets) > 0 {
+		payloads = append(payloads, p)
+	}
+
+	month, _ := periodStart(day, "month")
+	totals := &webhookTotals{Month: month.Format("2006-01"), Categories: map[string]int{}}
+	for _, p := range pp {
+		c := p.commodity
+		if c.isTransfer() || p.date.Before(month) || p.date.After(day) {
+			continue
+		}
+		if c.income {
+			totals.Income += c.price
+		} else {
+			totals.Expenses += c.price
+			totals.Categories[c.category] += c.price
+		}
+	}
+	return append(payloads, &webhookPayload{Event: "totals", Time: date, Totals: totals})
+}
+
+// $XDG_STATE_HOME/finparser/notified, $HOME/.local/state is used when XDG_STATE_HOME is not set
+func defaultNotifiedPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "finparser", "notified")
+}
+
+// Hash of message of the parse error. Row isn't hashed, so that errors
+// aren't new when rows are inserted above them.
+func errorHash(e *ParseError) string {
+	sum := sha256.Sum256([]byte(e.s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Read hashes of parse errors sent to webhooks by previous runs, one per line.
+// Missing file means nothing was sent yet.
+func loadNotified(path string) (map[string]bool, error) {
+	notified := map[string]bool{}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return notified, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			notified[line] = true
+		}
+	}
+	return notified, scanner.Err()
+}
+
+// Save hashes of current parse errors, fixed errors are forgotten
+// so that they are sent again if they come back
+func saveNotified(path string, errs []*ParseError) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		for _, e := range errs {
+			if _, err := fmt.Fprintln(w, errorHash(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Parse errors which weren't sent yet
+func newErrors(errs []*ParseError, notified map[string]bool) []*ParseError {
+	var result []*ParseError
+	for _, e := range errs {
+		if !notified[errorHash(e)] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Send payloads to webhooks subscribed to their events. With dry-run writer
+// payloads are written to it instead of being sent.
+func notify(hooks []Webhook, payloads []*webhookPayload, dryRun io.Writer) error {
+	var errs []error
+	for _, p := range payloads {
+		body, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		for i := range hooks {
+			h := &hooks[i]
+			if !slices.Contains(h.Events, p.Event) {
+				continue
+			}
+			if dryRun != nil {
+				fmt.Fprintf(dryRun, "POST %s\n%s\n", h.URL, body)
+				continue
+			}
+			if err := h.send(body); err != nil {
+				errs = append(errs, fmt.Errorf("webhook %s: %s event: %w", h.URL, p.Event, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Post body, retrying on network errors, 429 and 5xx responses
+func (h *Webhook) send(body []byte) error {
+	backoff := webhookBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		if retry, err = h.post(body); err == nil || !retry || attempt >= *h.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *Webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return false, nil
+}
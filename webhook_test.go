@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestCheckWebhooks(t *testing.T) {
+	hooks, err := checkWebhooks([]Webhook{
+		{URL: "https://example.com/hook"},
+		{URL: "http://localhost:8080/", Events: []string{"budget"}, Retries: intPtr(0)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, webhookEvents, hooks[0].Events)
+	assert.Equal(t, 3, *hooks[0].Retries)
+	assert.Equal(t, 0, *hooks[1].Retries)
+
+	for _, h := range []Webhook{
+		{URL: "example.com/hook"},
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com", Events: []string{"spam"}},
+		{URL: "https://example.com", Retries: intPtr(-1)},
+	} {
+		_, err := checkWebhooks([]Webhook{h})
+		assert.Error(t, err, h.URL)
+	}
+}
+
+func TestWebhookPayloads(t *testing.T) {
+	saveGlobals(t)
+	pp := budgetTestPurchases(t)
+	date := time.Date(2024, 3, 20, 21, 0, 0, 0, time.UTC)
+	bb := []Budget{
+		{Name: "продукты", Category: "продукты", Limit: 5000, Period: "month"},
+		{Name: "кафе", Category: "кафе", Limit: 5000, Period: "month"},
+	}
+	errs := []*ParseError{{"invalid price: abc", 7}}
+
+	payloads := pp.webhookPayloads(errs, bb, date)
+	assert.Equal(t, []*webhookPayload{
+		{Event: "errors", Time: date, Errors: []webhookError{{7, "invalid price: abc"}}},
+		{Event: "budget", Time: date, Budgets: []webhookBudget{{"продукты", "2024-03", 5000, 10500, 16275}}},
+		{Event: "totals", Time: date, Totals: &webhookTotals{
+			Month:      "2024-03",
+			Income:     100000,
+			Expenses:   10500,
+			Categories: map[string]int{"продукты": 10500},
+		}},
+	}, payloads)
+
+	payloads = pp.webhookPayloads(nil, bb, date.AddDate(0, 0, -10))
+	assert.Len(t, payloads, 1, "only totals without errors and exceeded budgets")
+	assert.Equal(t, 1500, payloads[0].Totals.Expenses)
+}
+
+func TestNotifiedErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "finparser", "notified")
+	notified, err := loadNotified(path)
+	assert.NoError(t, err)
+	assert.Empty(t, notified, "nothing is sent before the first run")
+
+	first := []*ParseError{{"invalid price: abc", 7}, {"unknown token x", 9}}
+	assert.Equal(t, first, newErrors(first, notified))
+	assert.NoError(t, saveNotified(path, first))
+
+	second := []*ParseError{{"invalid price: abc", 7}, {"unknown token y", 9}, {"invalid date", 12}}
+	notified, err = loadNotified(path)
+	assert.NoError(t, err)
+	assert.Equal(t, second[1:], newErrors(second, notified), "only new errors are sent")
+
+	assert.NoError(t, saveNotified(path, second[1:]))
+	notified, err = loadNotified(path)
+	assert.NoError(t, err)
+	assert.Equal(t, second[:1], newErrors(second, notified), "fixed errors are sent again when they come back")
+
+	moved := []*ParseError{{"unknown token y", 10}, {"invalid date", 13}}
+	assert.Empty(t, newErrors(moved, notified), "errors moved by inserted rows aren't new")
+}
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	statuses []int // responses to return, 200 after they run out
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestNotify(t *testing.T) {
+	backoff := webhookBackoff
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() {
+		webhookBackoff = backoff
+	})
+
+	date := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	payloads := []*webhookPayload{
+		{Event: "errors", Time: date, Errors: []webhookError{{7, "invalid price: abc"}}},
+		{Event: "totals", Time: date, Totals: &webhookTotals{Month: "2024-03", Categories: map[string]int{}}},
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		recorder := &webhookRecorder{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		hooks, err := checkWebhooks([]Webhook{{URL: server.URL, Events: []string{"errors"}, Headers: map[string]string{"Authorization": "Bearer secret"}}})
+		assert.NoError(t, err)
+		assert.NoError(t, notify(hooks, payloads, nil))
+		assert.Len(t, recorder.requests, 3)
+		req := recorder.requests[2]
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+
+		var received webhookPayload
+		assert.NoError(t, json.Unmarshal([]byte(recorder.bodies[2]), &received))
+		assert.Equal(t, *payloads[0], received)
+	})
+
+	t.Run("retries run out", func(t *testing.T) {
+		recorder := &webhookRecorder{statuses: []int{500, 502, 503}}
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		hooks, err := checkWebhooks([]Webhook{{URL: server.URL, Retries: intPtr(2)}})
+		assert.NoError(t, err)
+		err = notify(hooks, payloads, nil)
+		assert.ErrorContains(t, err, "errors event: unexpected status: 503 Service Unavailable")
+		assert.Len(t, recorder.requests, 4, "totals are sent after errors failed")
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		recorder := &webhookRecorder{statuses: []int{http.StatusBadRequest}}
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		hooks, err := checkWebhooks([]Webhook{{URL: server.URL, Events: []string{"errors"}}})
+		assert.NoError(t, err)
+		assert.Error(t, notify(hooks, payloads, nil))
+		assert.Len(t, recorder.requests, 1, "client errors aren't retried")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		recorder := &webhookRecorder{}
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		hooks, err := checkWebhooks([]Webhook{{URL: server.URL, Events: []string{"totals"}}})
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, notify(hooks, payloads, &buf))
+		assert.Empty(t, recorder.requests)
+		expected := "POST " + server.URL + "\n" +
+			`{"event":"totals","time":"2024-03-20T00:00:00Z","totals":{"month":"2024-03","income":0,"expenses":0,"categories":{}}}` + "\n"
+		assert.Equal(t, expected, buf.String())
+	})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	sheetPurchases       = "Purchases"
+	sheetMonthByCategory = "By month × category"
+	sheetPerson          = "By person"
+	sheetErrors          = "Errors"
+)
+
+// Built-in Excel number formats
+const (
+	xlsxDateFormat   = 14 // short date of the reader's locale
+	xlsxNumberFormat = 4  // #,##0.00
+)
+
+// Pivot of purchases with a row per month and a column per category
+func (pp Purchases) byMonthAndCategory() *table {
+	sums := map[string]map[string]float64{}
+	categories := map[string]bool{}
+	for _, p := range pp {
+		month := p.date.Format("2006-01")
+		if sums[month] == nil {
+			sums[month] = map[string]float64{}
+		}
+		sums[month][p.commodity.category] += float64(p.commodity.price)
+		categories[p.commodity.category] = true
+	}
+
+	t := &table{name: sheetMonthByCategory, columns: []column{{"Month", kindString}}}
+	for _, c := range sortedKeys(categories) {
+		t.columns = append(t.columns, column{c, kindDecimal})
+	}
+	t.columns = append(t.columns, column{"Total", kindDecimal})
+
+	for _, month := range sortedKeys(sums) {
+		row := []string{month}
+		var total float64
+		for _, c := range t.columns[1 : len(t.columns)-1] {
+			sum := sums[month][c.name]
+			total += sum
+			row = append(row, strconv.FormatFloat(sum, 'f', -1, 64))
+		}
+		t.rows = append(t.rows, append(row, strconv.FormatFloat(total, 'f', -1, 64)))
+	}
+	return t
+}
+
+// Count and sum of purchases per person
+func (pp Purchases) byPerson() *table {
+	counts := map[string]int{}
+	sums := map[string]float64{}
+	for _, p := range pp {
+		counts[p.commodity.person]++
+		sums[p.commodity.person] += float64(p.commodity.price)
+	}
+	t := &table{
+		name:    sheetPerson,
+		columns: []column{{"Person", kindString}, {"Count", kindInt}, {"Total", kindDecimal}},
+	}
+	for _, person := range sortedKeys(sums) {
+		t.rows = append(t.rows, []string{
+			person,
+			strconv.Itoa(counts[person]),
+			strconv.FormatFloat(sums[person], 'f', -1, 64),
+		})
+	}
+	return t
+}
+
+func errorsTable(errors []*ParseError) *table {
+	t := &table{name: sheetErrors, columns: []column{{"Row", kindInt}, {"Error", kindString}}}
+	for _, e := range errors {
+		t.rows = append(t.rows, []string{strconv.Itoa(e.row), e.s})
+	}
+	return t
+}
+
+// Convert table cell to typed value, so that Excel gets numbers and dates
+func xlsxValue(kind columnKind, s string) interface{} {
+	switch kind {
+	case kindInt:
+		if v, err := strconv.Atoi(s); err == nil {
+			return v
+		}
+	case kindDecimal:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	case kindDate:
+		if v, err := time.Parse(df, s); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+// Write table to sheet with header row, typed cells and autofilter
+func writeSheet(f *excelize.File, t *table, styles map[columnKind]int) error {
+	if _, err := f.NewSheet(t.name); err != nil {
+		return err
+	}
+	header := t.header()
+	if err := f.SetSheetRow(t.name, "A1", &header); err != nil {
+		return err
+	}
+	for r, row := range t.rows {
+		var values []interface{}
+		for i, c := range t.columns {
+			values = append(values, xlsxValue(c.kind, row[i]))
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, r+2)
+		if err := f.SetSheetRow(t.name, cell, &values); err != nil {
+			return err
+		}
+	}
+
+	last, _ := excelize.CoordinatesToCellName(len(t.columns), len(t.rows)+1)
+	if len(t.rows) > 0 {
+		for i, c := range t.columns {
+			style, ok := styles[c.kind]
+			if !ok {
+				continue
+			}
+			from, _ := excelize.CoordinatesToCellName(i+1, 2)
+			to, _ := excelize.CoordinatesToCellName(i+1, len(t.rows)+1)
+			if err := f.SetCellStyle(t.name, from, to, style); err != nil {
+				return err
+			}
+		}
+	}
+	if err := f.AutoFilter(t.name, "A1:"+last, nil); err != nil {
+		return err
+	}
+	return f.SetPanes(t.name, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
+
+// Write workbook with all entries, month × category pivot and per person totals of expenses, and parse errors
+func writeXlsx(w io.Writer, pp Purchases, errors []*ParseError) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: xlsxDateFormat})
+	if err != nil {
+		return err
+	}
+	numberStyle, err := f.NewStyle(&excelize.Style{NumFmt: xlsxNumberFormat})
+	if err != nil {
+		return err
+	}
+	styles := map[columnKind]int{kindDate: dateStyle, kindDecimal: numberStyle}
+
+	purchases := pp.toTable()
+	purchases.name = sheetPurchases
+	expenses := pp.expenses()
+	for _, t := range []*table{purchases, expenses.byMonthAndCategory(), expenses.byPerson(), errorsTable(errors)} {
+		if err := writeSheet(f, t, styles); err != nil {
+			return err
+		}
+	}
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return err
+	}
+	f.SetActiveSheet(0)
+
+	return f.Write(w)
+}
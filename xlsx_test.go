@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func xlsxTestPurchases() Purchases {
+	return Purchases{
+		&Purchase{
+			date:      time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "john", category: "food", name: "bread", price: 50},
+		},
+		&Purchase{
+			date:      time.Date(2023, 12, 16, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "mary", category: "транспорт", name: "автобус", price: 30},
+		},
+		&Purchase{
+			date:      time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+			commodity: &Commodity{person: "mary", category: "food", name: "milk", price: 90},
+		},
+	}
+}
+
+func TestByMonthAndCategory(t *testing.T) {
+	result := xlsxTestPurchases().byMonthAndCategory()
+	assert.Equal(t, []string{"Month", "food", "транспорт", "Total"}, result.header())
+	assert.Equal(t, [][]string{
+		{"2023-12", "50", "30", "80"},
+		{"2024-01", "90", "0", "90"},
+	}, result.rows)
+}
+
+func TestByPerson(t *testing.T) {
+	result := xlsxTestPurchases().byPerson()
+	assert.Equal(t, [][]string{
+		{"john", "1", "50"},
+		{"mary", "2", "120"},
+	}, result.rows)
+}
+
+func TestWriteXlsx(t *testing.T) {
+	df = "02.01.2006"
+
+	errors := []*ParseError{{"can't parse: хлеб", 7}}
+	var buf bytes.Buffer
+	assert.NoError(t, writeXlsx(&buf, xlsxTestPurchases(), errors))
+
+	f, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	assert.Equal(t, []string{"Purchases", "By month × category", "By person", "Errors"}, f.GetSheetList())
+
+	// Dates and prices are typed cells, not strings
+	cellType, err := f.GetCellType("Purchases", "A2")
+	assert.NoError(t, err)
+	assert.NotEqual(t, excelize.CellTypeSharedString, cellType)
+	raw, err := f.GetCellValue("Purchases", "A2", excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "45275", raw, "15.12.2023 as Excel serial date")
+	raw, err = f.GetCellValue("Purchases", "E4", excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "90", raw)
+
+	rows, err := f.GetRows("Purchases")
+	assert.NoError(t, err)
+	assert.Equal(t, xlsxTestPurchases().toTable().header(), rows[0])
+	assert.Len(t, rows, 4)
+
+	rows, err = f.GetRows("Errors")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Row", "Error"}, {"7", "can't parse: хлеб"}}, rows)
+
+	// Autofilter is stored as a hidden defined name
+	names := f.GetDefinedName()
+	assert.NotEmpty(t, names)
+	assert.Equal(t, "_xlnm._FilterDatabase", names[0].Name)
+}